@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+)
+
+// forwardedSignals are passed on to a child started by runChild.
+var forwardedSignals = []os.Signal{syscall.SIGTERM, syscall.SIGHUP, syscall.SIGUSR1, syscall.SIGUSR2}
+
+// terminalSignals come from the tty, which already delivers them to the
+// child as a member of porty's foreground process group. Forwarding them
+// too would make tools like npm treat one Ctrl-C as a second one.
+var terminalSignals = []os.Signal{syscall.SIGINT, syscall.SIGQUIT}
+
+// runChild runs args with env added to porty's environment and the
+// terminal passed through. started is called once the child is running.
+// Meanwhile porty outlives terminal signals, forwards the others to the
+// child, and returns its exit status (128+n when signal n killed it).
+func runChild(args, env []string, started func(pid int)) (int, error) {
+	c := exec.Command(args[0], args[1:]...)
+	c.Env = append(os.Environ(), env...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	sigs := make(chan os.Signal, 4)
+	signal.Notify(sigs, forwardedSignals...)
+	defer signal.Stop(sigs)
+	// Catching terminal signals keeps porty alive to report the child's
+	// status; nothing reads them.
+	held := make(chan os.Signal, 1)
+	signal.Notify(held, terminalSignals...)
+	defer signal.Stop(held)
+
+	if err := c.Start(); err != nil {
+		return 0, err
+	}
+	if started != nil {
+		started(c.Process.Pid)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case sig := <-sigs:
+				c.Process.Signal(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+	err := c.Wait()
+	close(done)
+
+	var ee *exec.ExitError
+	if errors.As(err, &ee) {
+		if ws, ok := ee.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			return 128 + int(ws.Signal()), nil
+		}
+		return ee.ExitCode(), nil
+	}
+	return 0, err
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/trishan9/porty/internal"
+)
+
+var completionCmd = &cobra.Command{
+	Use:   "completion bash|zsh|fish",
+	Short: "Generate a shell completion script",
+	Long: `Generate a shell completion script.
+
+Completions are dynamic: ports, PIDs, users, tags and process names are
+taken from a live scan when you press TAB.
+
+  bash:  source <(porty completion bash)
+         porty completion bash > /etc/bash_completion.d/porty
+  zsh:   porty completion zsh > "${fpath[1]}/_porty"
+  fish:  porty completion fish > ~/.config/fish/completions/porty.fish`,
+	Args:                  cobra.ExactArgs(1),
+	ValidArgs:             []string{"bash", "zsh", "fish"},
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch args[0] {
+		case "bash":
+			return rootCmd.GenBashCompletionV2(os.Stdout, true)
+		case "zsh":
+			return rootCmd.GenZshCompletion(os.Stdout)
+		case "fish":
+			return rootCmd.GenFishCompletion(os.Stdout, true)
+		}
+		return fmt.Errorf("unsupported shell %q (want bash, zsh or fish)", args[0])
+	},
+}
+
+// completePorts suggests listening ports annotated with their process.
+// Inside a comma-separated --port list it completes the last element.
+func completePorts(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	prefix := ""
+	if i := strings.LastIndex(toComplete, ","); i >= 0 {
+		prefix = toComplete[:i+1]
+	}
+	given := make(map[string]bool)
+	for _, a := range args {
+		given[a] = true
+	}
+	for _, p := range strings.Split(prefix, ",") {
+		given[p] = true
+	}
+
+	entries := scanPorts()
+	internal.SortEntries(entries, "port", false)
+	seen := make(map[string]bool)
+	var out []string
+	for _, e := range entries {
+		if seen[e.LocalPort] || given[e.LocalPort] {
+			continue
+		}
+		seen[e.LocalPort] = true
+		out = append(out, prefix+e.LocalPort+"\t"+describeEntry(e))
+	}
+	return out, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completePortsOrLabels suggests listening ports and, when no comma list
+// is being typed, the labels of listening ports.
+func completePortsOrLabels(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	out, directive := completePorts(cmd, args, toComplete)
+	if strings.Contains(toComplete, ",") {
+		return out, directive
+	}
+	seen := make(map[string]bool)
+	for _, a := range args {
+		seen[internal.Slug(a)] = true
+	}
+	for _, e := range scanPorts() {
+		slug := internal.Slug(e.Label)
+		if slug == "" || seen[slug] {
+			continue
+		}
+		seen[slug] = true
+		out = append(out, slug+"\t"+describeEntry(e)+", port "+e.LocalPort)
+	}
+	return out, directive
+}
+
+// completePIDs suggests PIDs that hold sockets.
+func completePIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	entries := scanPorts()
+	internal.SortEntries(entries, "pid", false)
+	ports := make(map[int][]string)
+	names := make(map[int]string)
+	var pids []int
+	for _, e := range entries {
+		if e.PID <= 0 {
+			continue
+		}
+		if ports[e.PID] == nil {
+			pids = append(pids, e.PID)
+			names[e.PID] = e.ProcessName
+		}
+		ports[e.PID] = append(ports[e.PID], e.LocalPort)
+	}
+	var out []string
+	for _, pid := range pids {
+		out = append(out, fmt.Sprintf("%d\t%s :%s", pid, names[pid], strings.Join(ports[pid], " :")))
+	}
+	return out, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeField returns a completion function suggesting the distinct
+// values of field in the current scan, plus extra.
+func completeField(field func(internal.PortEntry) string, extra ...string) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		entries := scanPorts()
+		seen := make(map[string]bool)
+		out := append([]string(nil), extra...)
+		var values []string
+		for _, e := range entries {
+			if v := field(e); v != "" && !seen[v] {
+				seen[v] = true
+				values = append(values, v)
+			}
+		}
+		sort.Strings(values)
+		return append(out, values...), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+func completeValues(values ...string) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return cobra.FixedCompletions(values, cobra.ShellCompDirectiveNoFileComp)
+}
+
+var (
+	completeUsers     = completeField(func(e internal.PortEntry) string { return e.UserName }, "me\tthe invoking user")
+	completeTags      = completeField(func(e internal.PortEntry) string { return e.Tag })
+	completeProcesses = completeField(func(e internal.PortEntry) string { return e.ProcessName })
+)
+
+func describeEntry(e internal.PortEntry) string {
+	if e.PID > 0 {
+		return e.ProcessName + " (PID " + strconv.Itoa(e.PID) + ", " + e.Proto + ")"
+	}
+	return e.ProcessName + " (" + e.Proto + ")"
+}
+
+func init() {
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
+	rootCmd.AddCommand(completionCmd)
+}
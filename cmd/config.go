@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/trishan9/porty/internal"
+	"github.com/trishan9/porty/tui"
+)
+
+var configFile string
+var configForce bool
+
+// cfg is the loaded configuration; commands read their defaults from it.
+var cfg = internal.DefaultConfig()
+
+// loadConfig reads the config file and applies its defaults to the flags
+// cmd was not given explicitly.
+func loadConfig(cmd *cobra.Command) error {
+	c, err := readConfig()
+	if err != nil {
+		return err
+	}
+	cfg = c
+
+	flags := cmd.Flags()
+	if !flags.Changed("helper") && cfg.Helper != "" {
+		helperCmd = cfg.Helper
+	}
+	if !flags.Changed("output") && !flags.Changed("json") && !flags.Changed("template") {
+		outputFlag = cfg.Output.Format
+	}
+	if outputFormat() == "template" && templateFlag == "" {
+		templateFlag = cfg.Output.Template
+	}
+	tui.SetTheme(cfg.Theme)
+	return nil
+}
+
+// readConfig loads and validates the config file, including the output
+// settings only the CLI knows about.
+func readConfig() (internal.Config, error) {
+	path := internal.ConfigPath(configFile)
+	c, err := internal.LoadConfig(path)
+	if err != nil {
+		return c, err
+	}
+	if f := c.Output.Format; f != "" && !contains(outputFormats, f) {
+		return c, fmt.Errorf("invalid config %s: output.format %q is not one of %s", path, f, strings.Join(outputFormats, ", "))
+	}
+	if _, err := tui.ParseColumns(c.Output.Columns); err != nil {
+		return c, fmt.Errorf("invalid config %s: output.columns: %w", path, err)
+	}
+	return c, nil
+}
+
+// protector returns the configured protected processes, or none with force.
+func protector(force bool) internal.Protector {
+	if force {
+		return internal.Protector{}
+	}
+	p, _ := internal.NewProtector(cfg.Protected)
+	return p
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the porty configuration file",
+	Long: `Manage the porty configuration file.
+
+The file lives at $XDG_CONFIG_HOME/porty/config.yaml (~/.config/porty/config.yaml),
+or wherever --config or PORTY_CONFIG point. Every key can be overridden with
+a PORTY_* environment variable named after it: refresh_interval is
+PORTY_REFRESH_INTERVAL, theme.blue is PORTY_THEME_BLUE, and lists such as
+protected take comma-separated values.`,
+	// A broken config file must not stop these commands from fixing it.
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return validateOutput()
+	},
+}
+
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Print the path of the config file",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println(internal.ConfigPath(configFile))
+	},
+}
+
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Write a config file with the defaults",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := internal.ConfigPath(configFile)
+		if err := internal.WriteDefaultConfig(path, configForce); err != nil {
+			return err
+		}
+		fmt.Println("wrote", path)
+		return nil
+	},
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get [key]",
+	Short: "Print a config value, or the whole effective config",
+	Args:  cobra.MaximumNArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return internal.ConfigKeys(), cobra.ShellCompDirectiveNoFileComp
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := readConfig()
+		if err != nil {
+			return err
+		}
+		if len(args) == 1 {
+			v, err := internal.ConfigValue(c, args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Println(v)
+			return nil
+		}
+		enc := yaml.NewEncoder(os.Stdout)
+		enc.SetIndent(2)
+		if err := enc.Encode(c); err != nil {
+			return err
+		}
+		return enc.Close()
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Set a config value in the config file",
+	Args:  cobra.ExactArgs(2),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return internal.ConfigKeys(), cobra.ShellCompDirectiveNoFileComp
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := internal.ConfigPath(configFile)
+		cmd.SilenceUsage = true
+		if err := internal.SetConfigValue(path, args[0], args[1]); err != nil {
+			return err
+		}
+		if _, err := readConfig(); err != nil {
+			return err
+		}
+		fmt.Printf("%s = %s (%s)\n", args[0], args[1], path)
+		return nil
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the config file and PORTY_* overrides",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := readConfig(); err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+		fmt.Println(internal.ConfigPath(configFile) + ": ok")
+		return nil
+	},
+}
+
+func init() {
+	configInitCmd.Flags().BoolVar(&configForce, "force", false, "Overwrite an existing config file")
+	configCmd.Example = `
+		porty config init
+		porty config set refresh_interval 5s
+		porty config set protected sshd,postgres
+		porty config get keys.kill
+		PORTY_SORT=pid porty config get sort
+		porty config validate
+		`
+	configCmd.AddCommand(configPathCmd, configInitCmd, configGetCmd, configSetCmd, configValidateCmd)
+	rootCmd.AddCommand(configCmd)
+}
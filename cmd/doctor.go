@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/trishan9/porty/internal"
+)
+
+var doctorManifest string
+var doctorStrict bool
+
+// Exit codes of `porty doctor`, worst first, for preflight checks.
+const (
+	exitDoctorConflict   = 2
+	exitDoctorMissing    = 3
+	exitDoctorUnexpected = 4
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the ports of this project's .porty.yml against the live scan",
+	Long: `Check the ports declared in this project's .porty.yml against the live scan.
+
+The manifest is found by walking up from the current directory, or given
+with --manifest. Each declared port expects to be free (the default) or
+listening:
+
+  ports:
+    - port: 3000
+      service: web
+    - port: 5432
+      service: postgres
+      expect: listening
+      process: postgres      # optional glob or /regex/ of the owner
+    - port: 5353
+      proto: udp
+
+porty doctor reports conflicts (a port held by an unrelated process, with
+its owner), missing services, and unexpected listeners: processes running
+from inside the project on ports the manifest does not declare.
+
+Exit status is 0 when everything matches, 2 on a conflict, 3 when a service
+is missing, and, with --strict, 4 when there are unexpected listeners.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		showBanner()
+		cmd.SilenceUsage = true
+
+		path := doctorManifest
+		if path == "" {
+			wd, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+			if path, err = internal.FindManifest(wd); err != nil {
+				return err
+			}
+		}
+		m, err := internal.LoadManifest(path)
+		if err != nil {
+			return err
+		}
+
+		findings := internal.Diagnose(m, scanPorts())
+		err = writeOutput(os.Stdout, doctorReport{Manifest: m.Path, Findings: findings}, findings, func() error {
+			printFindings(os.Stdout, m.Path, findings)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if code := doctorExitCode(findings); code != 0 {
+			cmd.SilenceErrors = true
+			return &exitError{code: code}
+		}
+		return nil
+	},
+}
+
+// doctorReport is the JSON shape of `porty doctor --json`.
+type doctorReport struct {
+	Manifest string             `json:"manifest"`
+	Findings []internal.Finding `json:"findings"`
+}
+
+func printFindings(w io.Writer, path string, findings []internal.Finding) {
+	fmt.Fprintf(w, "manifest %s\n\n", path)
+	if len(findings) == 0 {
+		fmt.Fprintln(w, "no ports declared")
+		return
+	}
+
+	marks := map[string]string{
+		internal.FindingOK:         "✓",
+		internal.FindingConflict:   "✗",
+		internal.FindingMissing:    "✗",
+		internal.FindingUnexpected: "?",
+	}
+	counts := make(map[string]int)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, f := range findings {
+		counts[f.Status]++
+		service := f.Service
+		if service == "" {
+			service = "-"
+		}
+		fmt.Fprintf(tw, "%s %s\t%d/%s\t%s\t%s\n", marks[f.Status], service, f.Port, f.Proto, f.Status, f.Message)
+		if f.Status == internal.FindingOK {
+			continue
+		}
+		for _, e := range f.Owners {
+			if cmdline := internal.ReadCmdline(e.PID); e.PID > 0 && cmdline != "" {
+				fmt.Fprintf(tw, "\t\t\t  PID %d: %s\n", e.PID, cmdline)
+			}
+		}
+	}
+	tw.Flush()
+
+	var summary []string
+	for _, status := range []string{internal.FindingConflict, internal.FindingMissing, internal.FindingUnexpected} {
+		if n := counts[status]; n > 0 {
+			summary = append(summary, status+": "+strconv.Itoa(n))
+		}
+	}
+	if len(summary) == 0 {
+		fmt.Fprintln(w, "\nall ports as expected")
+		return
+	}
+	fmt.Fprintln(w, "\n"+strings.Join(summary, ", "))
+}
+
+// doctorExitCode turns the worst finding into an exit code.
+func doctorExitCode(findings []internal.Finding) int {
+	code := 0
+	for _, f := range findings {
+		switch {
+		case f.Status == internal.FindingConflict:
+			return exitDoctorConflict
+		case f.Status == internal.FindingMissing:
+			code = exitDoctorMissing
+		case f.Status == internal.FindingUnexpected && doctorStrict && code == 0:
+			code = exitDoctorUnexpected
+		}
+	}
+	return code
+}
+
+func init() {
+	doctorCmd.Flags().StringVar(&doctorManifest, "manifest", "", "Manifest to check (default: the nearest "+internal.ManifestName+")")
+	doctorCmd.Flags().BoolVar(&doctorStrict, "strict", false, "Also fail when the project listens on undeclared ports")
+	doctorCmd.Example = `
+		porty doctor
+		porty doctor --strict
+		porty doctor --manifest deploy/.porty.yml --json
+		porty doctor && docker compose up
+		`
+	rootCmd.AddCommand(doctorCmd)
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/trishan9/porty/internal"
+)
+
+// elevatedCmd is the privileged half of the elevation path: an unprivileged
+// porty runs it through sudo (or --helper), passes the resolved targets on
+// stdin and reads a structured reply from stdout.
+var elevatedCmd = &cobra.Command{
+	Use:    "elevated",
+	Short:  "Internal: run an operation on behalf of an unprivileged porty",
+	Hidden: true,
+}
+
+var elevatedKillCmd = &cobra.Command{
+	Use:  "kill",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var req internal.ElevatedKillRequest
+		if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+			return fmt.Errorf("invalid kill request: %w", err)
+		}
+		results, err := internal.ServeElevatedKill(req)
+		if err != nil {
+			return err
+		}
+		if err := internal.AppendAudit(results, "elevated"); err != nil {
+			fmt.Fprintln(os.Stderr, "warning:", err)
+		}
+		if results == nil {
+			results = []internal.KillResult{}
+		}
+		return json.NewEncoder(os.Stdout).Encode(results)
+	},
+}
+
+var elevatedScanCmd = &cobra.Command{
+	Use:  "scan",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entries, err := internal.ListPorts()
+		if err != nil {
+			return err
+		}
+		return json.NewEncoder(os.Stdout).Encode(entries)
+	},
+}
+
+var elevatedReserveCmd = &cobra.Command{
+	Use:  "reserve",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		var req string
+		if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+			return fmt.Errorf("invalid reserve request: %w", err)
+		}
+		ranges, err := internal.ParsePortRanges(req)
+		if err != nil {
+			return err
+		}
+		if err := internal.SetReservedPorts(ranges); err != nil {
+			return err
+		}
+		kept, err := internal.ReservedPorts()
+		if err != nil {
+			return err
+		}
+		return json.NewEncoder(os.Stdout).Encode(internal.FormatRanges(kept))
+	},
+}
+
+func init() {
+	elevatedCmd.AddCommand(elevatedKillCmd, elevatedScanCmd, elevatedReserveCmd)
+	rootCmd.AddCommand(elevatedCmd)
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/trishan9/porty/internal"
+)
+
+var ephemeralTop int
+
+var ephemeralCmd = &cobra.Command{
+	Use:   "ephemeral",
+	Short: "Show the ephemeral port range, listeners inside it and its use",
+	Long: `Show the ephemeral port range, listeners inside it and its use.
+
+The kernel picks the local port of outgoing connections from
+ip_local_port_range, skipping ip_local_reserved_ports. A server whose fixed
+port lies in that range can find it taken by an outgoing connection when it
+restarts, and fail with EADDRINUSE; porty marks such listeners in every
+listing. Move them below the range or reserve their ports with
+porty reserve add.
+
+Local ports are only unique per destination, so utilization of outgoing
+connections is shown per remote address and port, busiest first.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := internal.EphemeralRange()
+		if err != nil {
+			return err
+		}
+		reserved, err := internal.ReservedPorts()
+		if err != nil {
+			return err
+		}
+		listeners := []internal.PortEntry{}
+		for _, e := range scanPorts() {
+			if e.Ephemeral {
+				listeners = append(listeners, e)
+			}
+		}
+		internal.SortEntries(listeners, "port", false)
+		usage := internal.EphemeralUsage(r)
+
+		report := ephemeralReport{
+			Range:        r.String(),
+			Size:         r.Hi - r.Lo + 1,
+			Reserved:     internal.FormatRanges(reserved),
+			Listeners:    listeners,
+			Destinations: usage,
+		}
+		return writeOutput(os.Stdout, report, usage, func() error {
+			printEphemeral(report)
+			return nil
+		})
+	},
+}
+
+// ephemeralReport is the JSON shape of `porty ephemeral --json`.
+type ephemeralReport struct {
+	Range        string                    `json:"range"`
+	Size         int                       `json:"size"`
+	Reserved     string                    `json:"reserved"`
+	Listeners    []internal.PortEntry      `json:"listeners"`
+	Destinations []internal.DestinationUse `json:"destinations"`
+}
+
+func printEphemeral(r ephemeralReport) {
+	reserved := r.Reserved
+	if reserved == "" {
+		reserved = "none"
+	}
+	fmt.Printf("ephemeral range  %s (%d ports)\n", r.Range, r.Size)
+	fmt.Printf("reserved         %s\n", reserved)
+
+	fmt.Println()
+	if len(r.Listeners) == 0 {
+		fmt.Println("no listeners inside the range")
+	} else {
+		fmt.Println("listeners inside the range (reserve them with porty reserve add, or move them below it):")
+		for _, e := range r.Listeners {
+			fmt.Printf("  %s/%s  %s\n", e.LocalPort, e.Proto, describeEntry(e))
+		}
+	}
+
+	fmt.Println()
+	if len(r.Destinations) == 0 {
+		fmt.Println("no outgoing TCP connections use the range")
+		return
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DESTINATION\tPORTS\tUSED")
+	for i, d := range r.Destinations {
+		if ephemeralTop > 0 && i == ephemeralTop {
+			fmt.Fprintf(tw, "… %d more\t\t\n", len(r.Destinations)-i)
+			break
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%.1f%%\n", d.Destination, d.Ports, d.Percent)
+	}
+	tw.Flush()
+}
+
+func init() {
+	ephemeralCmd.Flags().IntVar(&ephemeralTop, "top", 10, "Destinations to show in the table (0 for all)")
+	ephemeralCmd.Example = `
+		porty ephemeral
+		porty ephemeral --top 0
+		porty ephemeral --json | jq '.destinations[0]'
+		`
+	rootCmd.AddCommand(ephemeralCmd)
+}
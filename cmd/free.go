@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/trishan9/porty/internal"
+)
+
+var freeRange string
+var freeCount int
+var freeProto string
+var freeAddr string
+var freeHold bool
+
+var freeCmd = &cobra.Command{
+	Use:   "free [--hold -- command...]",
+	Short: "Print unused ports",
+	Long: `Print unused ports, lowest first, one per line.
+
+A port is free when nothing listens on it, it is not in
+/proc/sys/net/ipv4/ip_local_reserved_ports, and porty can bind it on --addr.
+Without --range, ports in the ephemeral range (ip_local_port_range) are
+skipped, since outgoing connections may take them at any time.
+
+Picking a port and starting the program that uses it races against anyone
+else doing the same. With --hold, porty keeps the ports bound until the
+command after -- has started, passing them in PORT (the first) and PORTS
+(all, comma-separated), then waits for it and exits with its status.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if freeHold != (len(args) > 0) {
+			return fmt.Errorf("--hold needs a command after --, and a command needs --hold")
+		}
+		opts := internal.FreeOptions{Count: freeCount, Proto: strings.ToLower(freeProto), Addr: freeAddr}
+		if opts.Proto != "tcp" && opts.Proto != "udp" {
+			return fmt.Errorf("invalid --proto %q: want tcp or udp", freeProto)
+		}
+		if freeCount < 1 {
+			return fmt.Errorf("--count must be at least 1")
+		}
+		if freeRange != "" {
+			ranges, err := internal.ParsePortRanges(freeRange)
+			if err != nil {
+				return err
+			}
+			opts.Ranges = ranges
+		}
+		cmd.SilenceUsage = true
+
+		ports, held, err := internal.FindFreePorts(opts)
+		if err != nil {
+			return err
+		}
+		if !freeHold {
+			internal.ReleasePorts(held)
+		}
+
+		rows := make([]freePort, len(ports))
+		list := make([]string, len(ports))
+		for i, p := range ports {
+			rows[i] = freePort{Port: p, Proto: opts.Proto, Addr: opts.Addr}
+			list[i] = strconv.Itoa(p)
+		}
+		out := os.Stdout
+		if freeHold {
+			out = os.Stderr
+		}
+		err = writeOutput(out, rows, rows, func() error {
+			fmt.Fprintln(out, strings.Join(list, "\n"))
+			return nil
+		})
+		if err != nil || !freeHold {
+			internal.ReleasePorts(held)
+			return err
+		}
+
+		env := []string{"PORT=" + list[0], "PORTS=" + strings.Join(list, ",")}
+		code, err := runChild(args, env, func(int) { internal.ReleasePorts(held) })
+		if err != nil {
+			internal.ReleasePorts(held)
+			return fmt.Errorf("failed to start %s: %w", args[0], err)
+		}
+		if code != 0 {
+			cmd.SilenceErrors = true
+			return &exitError{code: code}
+		}
+		return nil
+	},
+}
+
+// freePort is one port found by `porty free`.
+type freePort struct {
+	Port  int    `json:"port"`
+	Proto string `json:"proto"`
+	Addr  string `json:"addr,omitempty"`
+}
+
+func init() {
+	freeCmd.Flags().StringVar(&freeRange, "range", "", "Ports and ranges to pick from (default 1024-65535 outside the ephemeral range)")
+	freeCmd.Flags().IntVarP(&freeCount, "count", "n", 1, "How many ports to return")
+	freeCmd.Flags().StringVar(&freeProto, "proto", "tcp", "Protocol (tcp or udp)")
+	freeCmd.Flags().StringVar(&freeAddr, "addr", "", "Bind address to check (default every address)")
+	freeCmd.Flags().BoolVar(&freeHold, "hold", false, "Keep the ports bound until the command after -- starts")
+	freeCmd.RegisterFlagCompletionFunc("proto", completeValues("tcp", "udp"))
+	freeCmd.RegisterFlagCompletionFunc("addr", completeValues("127.0.0.1", "::1", "0.0.0.0", "::"))
+	freeCmd.Example = `
+		porty free
+		porty free --range 3000-3999 --count 3
+		porty free --proto udp --addr 127.0.0.1
+		PORT=$(porty free --range 8000-8999) npm run dev
+		porty free --hold --count 2 -- sh -c 'go test ./... -api-port $PORT'
+		`
+	rootCmd.AddCommand(freeCmd)
+}
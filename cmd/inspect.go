@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/trishan9/porty/internal"
+)
+
+var inspectPID bool
+
+var inspectCmd = &cobra.Command{
+	Use:   "inspect <port|label|pid>",
+	Short: "Show everything known about a port or process",
+	Long: `Show everything known about the sockets on a port and the processes
+holding them: bind address and family, socket inode, send and receive
+queues, established peers, and for each owner its command line, working
+directory, executable (flagged when deleted from disk), PORT-like
+environment variables, ancestry, cgroup, systemd unit or container, open
+file descriptors against their limit, and start time.
+
+A number is taken as a port when something listens on it, and as a PID
+otherwise; --pid forces the latter. Details of another user's process may
+need root.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completePortsOrLabels,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		entries := scanPorts()
+		in, err := inspect(entries, args[0])
+		if err != nil {
+			return err
+		}
+		return writeOutput(os.Stdout, in, in.Sockets, func() error {
+			printInspection(os.Stdout, in)
+			return nil
+		})
+	},
+}
+
+// inspect resolves arg to the sockets of a port (or label) or of a PID.
+func inspect(entries []internal.PortEntry, arg string) (internal.Inspection, error) {
+	pid, err := strconv.Atoi(arg)
+	if err == nil && !inspectPID {
+		in := internal.Inspect(entries, func(e internal.PortEntry) bool { return e.LocalPort == arg })
+		if len(in.Sockets) > 0 {
+			return in, nil
+		}
+	}
+	if err == nil {
+		if _, statErr := os.Stat(filepath.Join("/proc", arg)); statErr != nil {
+			if inspectPID {
+				return internal.Inspection{}, fmt.Errorf("no process has PID %d", pid)
+			}
+			return internal.Inspection{}, fmt.Errorf("nothing listens on port %d and no process has PID %d", pid, pid)
+		}
+		in := internal.Inspect(entries, func(e internal.PortEntry) bool { return e.PID == pid })
+		if len(in.Processes) == 0 {
+			in.Processes = append(in.Processes, internal.InspectProcess(pid))
+		}
+		return in, nil
+	}
+	if inspectPID {
+		return internal.Inspection{}, fmt.Errorf("invalid PID %q", arg)
+	}
+
+	ports, err := resolvePortArgs(entries, []string{arg})
+	if err != nil {
+		return internal.Inspection{}, err
+	}
+	ranges, err := internal.ParsePortRanges(strings.Join(ports, ","))
+	if err != nil {
+		return internal.Inspection{}, err
+	}
+	in := internal.Inspect(entries, func(e internal.PortEntry) bool {
+		port, _ := strconv.Atoi(e.LocalPort)
+		for _, r := range ranges {
+			if r.Contains(port) {
+				return true
+			}
+		}
+		return false
+	})
+	if len(in.Sockets) == 0 {
+		return in, fmt.Errorf("nothing listens on %s", arg)
+	}
+	return in, nil
+}
+
+func printInspection(w io.Writer, in internal.Inspection) {
+	for _, s := range in.Sockets {
+		fmt.Fprintf(w, "%s %s (%s) %s\n", s.Proto, net.JoinHostPort(s.LocalAddr, s.LocalPort), s.Family, s.State)
+		fmt.Fprintln(w, "  inode:     ", s.Inode)
+		queues := fmt.Sprintf("send %d, receive %d", s.TxQueue, s.RxQueue)
+		if s.State == "LISTEN" {
+			queues = fmt.Sprintf("send %d, accept backlog %d", s.TxQueue, s.RxQueue)
+		}
+		fmt.Fprintln(w, "  queues:    ", queues)
+		owner := "unknown (try sudo)"
+		if s.PID > 0 {
+			owner = fmt.Sprintf("%d %s", s.PID, s.ProcessName)
+		}
+		fmt.Fprintln(w, "  owner:     ", owner)
+		if s.Label != "" {
+			label := s.Label
+			if s.Note != "" {
+				label += " — " + s.Note
+			}
+			fmt.Fprintln(w, "  label:     ", label)
+		}
+		if s.Ephemeral {
+			fmt.Fprintln(w, "  warning:    inside the ephemeral range (see porty ephemeral)")
+		}
+		if s.Proto == "tcp" && s.State == "LISTEN" {
+			peers := "none"
+			if len(s.Peers) > 0 {
+				peers = fmt.Sprintf("%d: %s", len(s.Peers), strings.Join(s.Peers, ", "))
+			}
+			fmt.Fprintln(w, "  peers:     ", peers)
+		}
+		fmt.Fprintln(w)
+	}
+
+	for i, p := range in.Processes {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "process %d %s (user %s)\n", p.PID, p.Name, p.User)
+		fmt.Fprintln(w, "  cmdline:   ", p.Cmdline)
+		if p.Cwd != "" {
+			fmt.Fprintln(w, "  cwd:       ", p.Cwd)
+		}
+		if p.Exe != "" {
+			exe := p.Exe
+			if p.ExeDeleted {
+				exe += " (deleted: the binary was replaced or removed since it started)"
+			}
+			fmt.Fprintln(w, "  exe:       ", exe)
+		}
+		if p.Started != nil {
+			fmt.Fprintf(w, "  started:    %s (%s ago)\n", p.Started.Format("2006-01-02 15:04:05"), time.Since(*p.Started).Round(time.Second))
+		}
+		fds := strconv.Itoa(p.FDs)
+		if p.FDLimit > 0 {
+			fds += fmt.Sprintf(" of %d", p.FDLimit)
+		}
+		fmt.Fprintln(w, "  open fds:  ", fds)
+		if len(p.PortEnv) > 0 {
+			fmt.Fprintln(w, "  port env:  ", strings.Join(p.PortEnv, " "))
+		}
+		fmt.Fprintln(w, "  spawned by:", p.Origin.ChainString())
+		if p.Origin.Cgroup != "" && p.Origin.Cgroup != "/" {
+			fmt.Fprintln(w, "  cgroup:    ", p.Origin.Cgroup)
+		}
+		if p.Origin.Unit != "" {
+			unit := p.Origin.Unit
+			if p.Origin.UserUnit {
+				unit += " (user)"
+			}
+			fmt.Fprintln(w, "  unit:      ", unit)
+		}
+		if p.Origin.Container != "" {
+			fmt.Fprintln(w, "  container: ", p.Origin.Container)
+		}
+		if len(p.Unreadable) > 0 {
+			fmt.Fprintf(w, "  unreadable: %s (try sudo)\n", strings.Join(p.Unreadable, ", "))
+		}
+	}
+}
+
+func init() {
+	inspectCmd.Flags().BoolVar(&inspectPID, "pid", false, "Treat the argument as a PID even if a port by that number is listening")
+	inspectCmd.Example = `
+		porty inspect 3000
+		porty inspect api
+		porty inspect --pid 4242
+		porty inspect 3000 --json
+		`
+	rootCmd.AddCommand(inspectCmd)
+}
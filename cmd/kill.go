@@ -2,7 +2,11 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/trishan9/porty/internal"
@@ -10,47 +14,376 @@ import (
 
 var ports string
 var pids string
+var killTree bool
+var killParent bool
+var killNoVerify bool
+var killTimeout time.Duration
+var killWatch time.Duration
+var killElevate bool
+var killSel internal.Selector
+var killWhere string
+var killForce bool
+var killYes bool
+var killConfirmOver int
+
+// Exit codes of `porty kill`, so scripts can tell why a port is not free.
+const (
+	exitPortHeld    = 2
+	exitPortRebound = 3
+)
 
 var killCmd = &cobra.Command{
-	Use:   "kill",
+	Use:   "kill [port|label...]",
 	Short: "Kill processes by port or PID",
+	Long: `Kill processes by port or PID.
+
+After signalling, porty rescans until every affected port is released or
+--timeout expires, then keeps watching for --watch in case a supervisor
+(nodemon, pm2, systemd, docker) rebinds it, and says what to stop instead.
+Exit status is 0 when all ports were freed, 2 when a
+killed process still holds one, and 3 when another process rebound it.
+
+Selectors (--port ranges, --name, --user, --tag, --proto, --addr, --where)
+combine with AND; with --pid they narrow the PIDs to their matching sockets.
+The matched set is always printed, and confirmation is required when more
+than --confirm-over processes match, unless --yes is given. A positional
+argument that is not a port is looked up as a label (see porty label).
+
+With --json or --output yaml, one result per target is printed along with
+each port's fate; ndjson, csv, tsv and --template get one row per target.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		showBanner()
-		
-		if ports == "" && pids == "" {
-			return fmt.Errorf("you must specify --port or --pid")
+
+		entries := scanPorts()
+
+		args, err := resolvePortArgs(entries, args)
+		if err != nil {
+			return err
+		}
+		sel := killSel
+		if spec := strings.Join(append(args, ports), ","); strings.Trim(spec, ",") != "" {
+			ranges, err := internal.ParsePortRanges(spec)
+			if err != nil {
+				return err
+			}
+			sel.Ports = ranges
+		}
+		where, err := parseWhere(killWhere)
+		if err != nil {
+			return err
+		}
+		sel.Where = where
+		if sel.Empty() && pids == "" {
+			return fmt.Errorf("you must specify --port, --pid or a selector (--name, --user, --tag, --proto, --addr, --where)")
+		}
+		if err := sel.Compile(); err != nil {
+			return err
 		}
 
-		entries, _ := internal.ListPorts()
+		info := infoWriter()
 
+		var targets []internal.KillTarget
+		var matched []internal.PortEntry
+		var unattributed []internal.SocketTarget
 		if pids != "" {
 			pidList := internal.ParseCSVInts(pids)
-			msgs := internal.KillPIDs(pidList)
-			for _, m := range msgs {
-				fmt.Println(m)
+			matched = entriesOf(entries, pidList)
+			if sel.Empty() {
+				targets = internal.TargetsForPIDs(entries, pidList)
+			} else {
+				// Selectors narrow the PIDs to their matching sockets.
+				matched = sel.Select(matched)
+				targets = internal.TargetsForEntries(matched)
+			}
+		} else {
+			matched = sel.Select(entries)
+			targets = internal.TargetsForEntries(matched)
+			if !internal.IsRoot() {
+				unattributed = internal.UnattributedSockets(matched)
 			}
-			return nil
 		}
 
-		if ports != "" {
-			portList := strings.Split(ports, ",")
-			msgs := internal.KillByPorts(entries, portList)
-			for _, m := range msgs {
-				fmt.Println(m)
+		if len(matched) > 0 {
+			fmt.Fprintf(info, "Matched %d socket(s):\n", len(matched))
+			printMatched(info, matched)
+			fmt.Fprintln(info)
+		}
+		if n := len(pidsOf(targets)); n > killConfirmOver && !killYes {
+			ok, err := confirm(fmt.Sprintf("Kill %d processes?", n))
+			if err != nil {
+				cmd.SilenceUsage = true
+				return err
 			}
+			if !ok {
+				fmt.Fprintln(info, "aborted")
+				return nil
+			}
+		}
+
+		var results []internal.KillResult
+		if killTree || killParent {
+			plans := make([]internal.KillPlan, 0, len(targets))
+			for _, t := range targets {
+				plan := internal.PlanKill(t.PID, killTree, killParent)
+				if killParent && plan.Supervisor == 0 {
+					fmt.Fprintf(info, "PID %d: no supervisor found, signalling it directly\n", t.PID)
+				}
+				plans = append(plans, plan)
+			}
+
+			if len(plans) > 0 {
+				fmt.Fprintln(info, "Signalling:")
+				for _, p := range plans {
+					fmt.Fprintln(info, p.Render())
+				}
+				fmt.Fprintln(info)
+			}
+			results = killGuarded(internal.PlanTargets(plans))
+		} else {
+			results = killGuarded(targets)
+		}
+
+		results, err = elevateKill(info, results, unattributed)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "warning:", err)
+		}
+		watch := internal.SignalledPorts(matched, results)
+
+		if err := internal.AppendAudit(results, "cli"); err != nil {
+			fmt.Fprintln(os.Stderr, "warning:", err)
+		}
+
+		var statuses []internal.PortStatus
+		if !killNoVerify && len(results) > 0 && len(watch) > 0 {
+			statuses = internal.WaitPortsFreed(entries, watch, killTimeout, 100*time.Millisecond)
+			statuses = internal.WatchRespawns(statuses, entries, killWatch, 100*time.Millisecond)
+		}
+
+		if results == nil {
+			results = []internal.KillResult{}
+		}
+		err = writeOutput(os.Stdout, killReport{Results: results, Ports: statuses}, results, func() error {
+			if len(results) == 0 {
+				if pids != "" && !sel.Empty() {
+					fmt.Println("no socket of the given PIDs matches the selectors")
+				} else if pids != "" {
+					fmt.Println("no valid PIDs to kill")
+				} else {
+					fmt.Println("no matching PIDs for given ports")
+				}
+			}
+			printKillResults(os.Stdout, results)
+			for _, st := range statuses {
+				printPortStatus(os.Stdout, st)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if code := killExitCode(results, statuses); code != 0 {
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+			return &exitError{code: code}
 		}
 		return nil
 	},
 }
 
+// killGuarded signals targets, skipping protected processes unless --force.
+func killGuarded(targets []internal.KillTarget) []internal.KillResult {
+	allowed, skipped := protector(killForce).Guard(targets)
+	return append(skipped, internal.KillTargets(allowed)...)
+}
+
+// killReport is the JSON shape of `porty kill --json`.
+type killReport struct {
+	Results []internal.KillResult `json:"results"`
+	Ports   []internal.PortStatus `json:"ports,omitempty"`
+}
+
+// printKillResults renders one table row per signalled target.
+func printKillResults(w io.Writer, results []internal.KillResult) {
+	if len(results) == 0 {
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PID\tPORT\tPROCESS\tSIGNAL\tOUTCOME\tTIME\tERROR")
+	for _, r := range results {
+		port := r.Port
+		if port == "" {
+			port = "-"
+		}
+		errMsg := "-"
+		if r.Err != nil {
+			errMsg = r.Err.Error()
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			r.PID, port, r.Process, r.Signal, r.Outcome, r.Duration.Round(time.Microsecond), errMsg)
+	}
+	tw.Flush()
+}
+
+// elevateKill offers to redo, through the privilege helper, the part of a
+// kill that failed for lack of permission: targets that got EPERM and
+// matched sockets whose owners porty could not see. The elevated porty
+// re-validates both and its results replace the failed ones.
+func elevateKill(info io.Writer, results []internal.KillResult, unattributed []internal.SocketTarget) ([]internal.KillResult, error) {
+	req := internal.ElevatedKillRequest{
+		Targets:   internal.PermissionDenied(results),
+		Sockets:   unattributed,
+		Protected: cfg.Protected,
+		Force:     killForce,
+	}
+	if len(req.Targets) == 0 && len(req.Sockets) == 0 {
+		return results, nil
+	}
+
+	if len(req.Sockets) > 0 {
+		names := make([]string, len(req.Sockets))
+		for i, s := range req.Sockets {
+			names[i] = s.String()
+		}
+		fmt.Fprintf(info, "%s held by processes porty cannot see (another user's?)\n", strings.Join(names, ", "))
+	}
+	if len(req.Targets) > 0 {
+		fmt.Fprintf(info, "%d target(s) failed with permission denied\n", len(req.Targets))
+	}
+	if !killElevate {
+		ok, err := confirm(fmt.Sprintf("Retry through %s?", helperCmd))
+		if err != nil {
+			fmt.Fprintln(info, "re-run with --elevate to retry through", helperCmd)
+			return results, nil
+		}
+		if !ok {
+			return results, nil
+		}
+	}
+
+	retried, err := internal.ElevatedKill(helperCmd, req)
+	if err != nil {
+		return results, err
+	}
+	return internal.MergeResults(results, retried), nil
+}
+
+// entriesOf returns the entries owned by any of pids.
+func entriesOf(entries []internal.PortEntry, pids []int) []internal.PortEntry {
+	want := make(map[int]bool, len(pids))
+	for _, pid := range pids {
+		want[pid] = true
+	}
+	var out []internal.PortEntry
+	for _, e := range entries {
+		if want[e.PID] {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// printPortStatus prints a port's fate and, for a rebound port, the chain
+// that respawned it and what to stop instead.
+func printPortStatus(w io.Writer, st internal.PortStatus) {
+	fmt.Fprintln(w, st)
+	if st.Origin == nil {
+		return
+	}
+	fmt.Fprintln(w, "  spawned by:", st.Origin.ChainString())
+	if st.Origin.Cgroup != "" && st.Origin.Cgroup != "/" {
+		fmt.Fprintln(w, "  cgroup:    ", st.Origin.Cgroup)
+	}
+	fmt.Fprintln(w, "  suggestion:", st.Origin.Suggestion())
+}
+
+// killExitCode turns the worst port fate into an exit code. Without ports
+// to verify, any target that was not signalled fails the command.
+func killExitCode(results []internal.KillResult, statuses []internal.PortStatus) int {
+	code := 0
+	for _, st := range statuses {
+		switch st.State {
+		case internal.PortRebound:
+			code = exitPortRebound
+		case internal.PortHeld:
+			if code == 0 {
+				code = exitPortHeld
+			}
+		}
+	}
+	if code != 0 || len(statuses) > 0 {
+		return code
+	}
+	for _, r := range results {
+		if !r.OK() {
+			return 1
+		}
+	}
+	return 0
+}
+
+// printMatched lists the sockets a selector matched.
+func printMatched(w io.Writer, entries []internal.PortEntry) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PORT\tPROTO\tADDR\tPID\tPROCESS\tUSER\tTAG")
+	for _, e := range entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			e.LocalPort, e.Proto, e.LocalAddr, e.PID, e.ProcessName, e.UserName, e.Tag)
+	}
+	tw.Flush()
+}
+
+// pidsOf returns the distinct PIDs of targets.
+func pidsOf(targets []internal.KillTarget) []int {
+	seen := make(map[int]bool)
+	var out []int
+	for _, t := range targets {
+		if !seen[t.PID] {
+			seen[t.PID] = true
+			out = append(out, t.PID)
+		}
+	}
+	return out
+}
+
 func init() {
-	killCmd.Flags().StringVar(&ports, "port", "", "Ports to kill (comma-separated)")
+	killCmd.Flags().StringVar(&ports, "port", "", "Ports or ranges to kill (e.g. 3000,8000-8999,9000-)")
 	killCmd.Flags().StringVar(&pids, "pid", "", "PIDs to kill (comma-separated)")
+	killCmd.Flags().BoolVar(&killTree, "tree", false, "Also kill every descendant of the target")
+	killCmd.Flags().BoolVar(&killParent, "parent", false, "Kill the nearest non-shell ancestor first (e.g. npm, nodemon)")
+	killCmd.Flags().BoolVar(&killParent, "supervisor", false, "Alias for --parent")
+	killCmd.Flags().BoolVar(&killNoVerify, "no-verify", false, "Don't wait for the ports to be released")
+	killCmd.Flags().DurationVar(&killTimeout, "timeout", 3*time.Second, "How long to wait for the ports to be released")
+	killCmd.Flags().DurationVar(&killWatch, "watch", 1500*time.Millisecond, "How long to watch freed ports for a respawn (0 to skip)")
+	killCmd.Flags().BoolVar(&killElevate, "elevate", false, "Retry permission failures through --helper (sudo) without asking")
+	killCmd.Flags().StringVar(&killSel.Name, "name", "", "Process name or cmdline glob, or /regex/")
+	killCmd.Flags().StringVar(&killSel.User, "user", "", "Owning user (\"me\" for yourself)")
+	killCmd.Flags().StringVar(&killSel.Tag, "tag", "", "Tag (USER, SYSTEM, KERNEL, SELF)")
+	killCmd.Flags().StringVar(&killSel.Proto, "proto", "", "Protocol (tcp or udp)")
+	killCmd.Flags().StringVar(&killSel.Addr, "addr", "", "Bind address: IP, CIDR, loopback or wildcard")
+	killCmd.Flags().StringVar(&killWhere, "where", "", "Filter expression, e.g. 'port >= 3000 && user == me && !(process ~ \"docker\")'")
+	killCmd.Flags().BoolVarP(&killForce, "force", "f", false, "Also kill processes the config file protects")
+	killCmd.Flags().BoolVarP(&killYes, "yes", "y", false, "Don't ask for confirmation")
+	killCmd.Flags().IntVar(&killConfirmOver, "confirm-over", 3, "Ask for confirmation when more processes than this match")
 	killCmd.Example = `
 		porty kill 3000
 		porty kill --force 8080
 		porty kill --pid 1234
 		porty kill 3000 8081 9090
+		porty kill --port 3000 --tree
+		porty kill --port 3000 --parent --tree
+		porty kill --port 3000 --timeout 10s && npm run dev
+		porty kill --port 3000 --json | jq '.results[].outcome'
+		porty kill --name 'python*' --user me
+		porty kill --addr wildcard --port 8001-
 		`
+	killCmd.ValidArgsFunction = completePortsOrLabels
+	killCmd.RegisterFlagCompletionFunc("port", completePorts)
+	killCmd.RegisterFlagCompletionFunc("pid", completePIDs)
+	killCmd.RegisterFlagCompletionFunc("name", completeProcesses)
+	killCmd.RegisterFlagCompletionFunc("user", completeUsers)
+	killCmd.RegisterFlagCompletionFunc("tag", completeTags)
+	killCmd.RegisterFlagCompletionFunc("proto", completeValues("tcp", "udp"))
+	killCmd.RegisterFlagCompletionFunc("addr", completeValues("loopback", "wildcard"))
 	rootCmd.AddCommand(killCmd)
 }
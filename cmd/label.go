@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/trishan9/porty/internal"
+)
+
+var labelProcess string
+var labelNote string
+var labelRemove bool
+
+var labelCmd = &cobra.Command{
+	Use:   "label [port] [name]",
+	Short: "Name a port so it can be recognised and addressed by label",
+	Long: `Name a port so it can be recognised and addressed by label.
+
+Labels are stored in $XDG_DATA_HOME/porty/labels.json
+(~/.local/share/porty/labels.json) and shown in the TUI, the plain table and
+JSON. With --process, the label only applies while that process holds the
+port. A labelled port can be addressed by its label, e.g. porty kill api-gateway.
+
+Without arguments, the stored labels are listed.`,
+	Args: cobra.MaximumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch {
+		case len(args) == 0:
+			labels, err := internal.LoadLabels()
+			if err != nil {
+				return err
+			}
+			if labels == nil {
+				labels = []internal.Label{}
+			}
+			return writeOutput(os.Stdout, labels, labels, func() error {
+				if len(labels) == 0 {
+					fmt.Println(`no labels; add one with porty label 3000 "web"`)
+					return nil
+				}
+				return printLabels(labels)
+			})
+
+		case labelRemove:
+			ok, err := internal.RemoveLabel(args[0], labelProcess)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("port %s has no label", args[0])
+			}
+			fmt.Printf("label of port %s removed\n", args[0])
+			return nil
+
+		case len(args) == 1:
+			return fmt.Errorf("give the label after the port, or --remove")
+		}
+
+		l := internal.Label{Port: args[0], Process: labelProcess, Name: args[1], Note: labelNote}
+		if err := internal.SetLabel(l); err != nil {
+			return err
+		}
+		fmt.Printf("port %s labelled %q (porty kill %s)\n", l.Port, l.Name, internal.Slug(l.Name))
+		return nil
+	},
+}
+
+func printLabels(labels []internal.Label) error {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PORT\tPROCESS\tLABEL\tNOTE")
+	for _, l := range labels {
+		process := l.Process
+		if process == "" {
+			process = "*"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", l.Port, process, l.Name, l.Note)
+	}
+	return tw.Flush()
+}
+
+// resolvePortArgs turns kill's positional arguments into a port list.
+// Ports and ranges pass through; any other argument is a label, resolved
+// to the ports currently listening under it.
+func resolvePortArgs(entries []internal.PortEntry, args []string) ([]string, error) {
+	var out []string
+	var labels []internal.Label
+	for _, a := range args {
+		if _, err := internal.ParsePortRanges(a); err == nil {
+			out = append(out, a)
+			continue
+		}
+		if labels == nil {
+			var err error
+			if labels, err = internal.LoadLabels(); err != nil {
+				return nil, err
+			}
+		}
+		if len(internal.FindLabels(labels, a)) == 0 {
+			return nil, fmt.Errorf("%q is neither a port nor a label (see porty label)", a)
+		}
+		found := make(map[string]bool)
+		for _, e := range entries {
+			if e.Label != "" && internal.Slug(e.Label) == internal.Slug(a) && !found[e.LocalPort] {
+				out = append(out, e.LocalPort)
+				found[e.LocalPort] = true
+			}
+		}
+		if len(found) == 0 {
+			return nil, fmt.Errorf("nothing labelled %q is listening", a)
+		}
+	}
+	return out, nil
+}
+
+func init() {
+	labelCmd.Flags().StringVar(&labelProcess, "process", "", "Only label the port while this process holds it")
+	labelCmd.Flags().StringVar(&labelNote, "note", "", "A longer note shown in JSON and porty label")
+	labelCmd.Flags().BoolVar(&labelRemove, "remove", false, "Remove the port's label")
+	labelCmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return completePorts(cmd, args, toComplete)
+	}
+	labelCmd.RegisterFlagCompletionFunc("process", completeProcesses)
+	labelCmd.Example = `
+		porty label 3001 "api gateway"
+		porty label 5433 test-db --process postgres --note "docker compose -f test.yml"
+		porty label 3001 --remove
+		porty label
+		porty kill api-gateway
+		`
+	rootCmd.AddCommand(labelCmd)
+}
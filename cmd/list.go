@@ -4,32 +4,138 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/trishan9/porty/internal"
 	"github.com/trishan9/porty/tui"
 )
 
+var listElevate bool
+var listPlain bool
+var listColumns string
+var listNoHeader bool
+var listSchema bool
+var listPorts string
+var listSel internal.Selector
+var listSort string
+var listReverse bool
+var listWhere string
+var listNoMerge bool
+
+//go:generate sh -c "cd .. && go run . list --schema > schema/list.v1.json"
+
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "Display active ports in an interactive TUI",
+	Long: `Display active ports in an interactive TUI.
+
+When stdout is not a terminal (a pipe, a file, a non-TTY ssh session), or
+with --plain, porty prints a static table instead. Colors are used only on
+a terminal and never when NO_COLOR is set.
+
+--json and --output yaml wrap the ports in a versioned envelope with the
+host, kernel, porty version, scan time and diagnostics. --schema prints
+its JSON Schema.
+
+Filters (--port ranges, --proto, --user, --tag, --process, --addr, --state,
+--where) combine with AND and apply to every output, including the TUI,
+where they become the initial filter shown by /. Without any, the config
+file's filter applies; its sort, columns and output format are defaults too.
+
+Sockets one process holds on the same port and protocol, such as a server
+bound to both 0.0.0.0 and [::], are merged into one row whose STACK is
+dual and whose addr column lists every bind address; --no-merge shows each
+socket on its own row.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if listSchema {
+			b, err := json.MarshalIndent(internal.SnapshotSchema(), "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(b))
+			return nil
+		}
+
+		plain := listPlain || !isTerminal(os.Stdout) || outputFormat() != ""
 		showBanner()
-		entries, err := internal.ListPorts()
+
+		flags := cmd.Flags()
+		if !flags.Changed("columns") {
+			listColumns = cfg.Output.Columns
+		}
+		if !flags.Changed("sort") {
+			listSort = cfg.Sort
+		}
+		if !flags.Changed("reverse") {
+			listReverse = cfg.Reverse
+		}
+		cols, err := tui.ParseColumns(listColumns)
 		if err != nil {
 			return err
 		}
 
-		if jsonOutput {
-			b, err := json.MarshalIndent(entries, "", "  ")
-			if err != nil {
+		sel := listSel
+		if listPorts != "" {
+			if sel.Ports, err = internal.ParsePortRanges(listPorts); err != nil {
 				return err
 			}
-			fmt.Println(string(b))
-			return nil
+		}
+		where := listWhere
+		if sel.Empty() && where == "" {
+			where = cfg.Filter
+		}
+		if sel.Where, err = parseWhere(where); err != nil {
+			return err
+		}
+		if err := sel.Compile(); err != nil {
+			return err
+		}
+
+		start := time.Now()
+		entries, err := internal.ListPorts()
+		if err != nil {
+			return err
+		}
+		if listElevate && !internal.IsRoot() {
+			if entries, err = internal.ElevatedScan(helperCmd); err != nil {
+				return err
+			}
+		}
+		took := time.Since(start)
+
+		decorate(entries)
+		entries = sel.Select(entries)
+		if !listNoMerge {
+			entries = internal.MergeStacks(entries)
+		}
+		if err := internal.SortEntries(entries, listSort, listReverse); err != nil {
+			return err
+		}
+
+		if plain {
+			snap := internal.NewSnapshot(version, entries, start, took)
+			return writeOutput(os.Stdout, snap, entries, func() error {
+				return tui.WriteTable(os.Stdout, entries, tui.TableOptions{
+					Columns: cols,
+					Header:  !listNoHeader,
+					Color:   isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == "",
+				})
+			})
 		}
 
-		if err := tui.Run(entries); err != nil {
+		if err := tui.Run(entries, tui.Options{
+			Helper:    helperCmd,
+			Filter:    sel,
+			Sort:      listSort,
+			Reverse:   listReverse,
+			Refresh:   cfg.RefreshInterval,
+			Keys:      cfg.Keys,
+			Protected: protector(false),
+			Tags:      cfg.Tags,
+			NoMerge:   listNoMerge,
+		}); err != nil {
 			fmt.Fprintln(os.Stderr, "TUI error:", err)
 		}
 		return nil
@@ -37,5 +143,42 @@ var listCmd = &cobra.Command{
 }
 
 func init() {
+	listCmd.Flags().BoolVar(&listElevate, "elevate", false, "Scan through --helper (sudo) to attribute other users' sockets")
+	listCmd.Flags().BoolVar(&listPlain, "plain", false, "Print a static table instead of the TUI")
+	listCmd.Flags().StringVar(&listColumns, "columns", "", "Table columns, comma-separated ("+strings.Join(tui.ColumnNames(), ",")+")")
+	listCmd.Flags().BoolVar(&listNoHeader, "no-header", false, "Omit the table header")
+	listCmd.Flags().StringVar(&listPorts, "port", "", "Only these ports or ranges (e.g. 3000,8000-8999,9000-)")
+	listCmd.Flags().StringVar(&listSel.Proto, "proto", "", "Only this protocol (tcp or udp)")
+	listCmd.Flags().StringVar(&listSel.User, "user", "", "Only ports owned by this user (\"me\" for yourself)")
+	listCmd.Flags().StringVar(&listSel.Tag, "tag", "", "Only this tag (USER, SYSTEM, KERNEL, SELF)")
+	listCmd.Flags().StringVar(&listSel.Name, "process", "", "Process name or cmdline glob, or /regex/")
+	listCmd.Flags().StringVar(&listSel.Addr, "addr", "", "Bind address: IP, CIDR, loopback or wildcard")
+	listCmd.Flags().StringVar(&listSel.State, "state", "", "Only this socket state (LISTEN, or UNCONN for udp)")
+	listCmd.Flags().StringVar(&listWhere, "where", "", "Filter expression, e.g. 'proto == \"tcp\" && port in 3000-3999'")
+	listCmd.Flags().StringVar(&listSort, "sort", "port", "Sort by "+strings.Join(internal.SortKeys, "|"))
+	listCmd.Flags().BoolVar(&listReverse, "reverse", false, "Reverse the sort order")
+	listCmd.Flags().BoolVar(&listNoMerge, "no-merge", false, "Show IPv4 and IPv6 sockets of one process and port as separate rows")
+	listCmd.Flags().BoolVar(&listSchema, "schema", false, "Print the JSON Schema of --json output and exit")
+	listCmd.Example = `
+		porty list
+		porty list | grep 3000
+		porty list --plain --columns port,process,pid --no-header
+		porty list -o csv > ports.csv
+		porty list --json --schema
+		porty list --plain --no-merge --columns port,stack,addr,pid
+		porty list --port 3000-3999 --proto tcp --sort process
+		porty list --process 'node*' --addr wildcard --json
+		porty list --where 'port >= 3000 && port < 4000 && user == me && !(process ~ "docker")'
+		porty list -o template --template '{{.LocalPort}} {{.ProcessName}}'
+		`
+	listCmd.RegisterFlagCompletionFunc("port", completePorts)
+	listCmd.RegisterFlagCompletionFunc("process", completeProcesses)
+	listCmd.RegisterFlagCompletionFunc("user", completeUsers)
+	listCmd.RegisterFlagCompletionFunc("tag", completeTags)
+	listCmd.RegisterFlagCompletionFunc("proto", completeValues("tcp", "udp"))
+	listCmd.RegisterFlagCompletionFunc("addr", completeValues("loopback", "wildcard"))
+	listCmd.RegisterFlagCompletionFunc("state", completeValues("LISTEN", "UNCONN"))
+	listCmd.RegisterFlagCompletionFunc("sort", completeValues(internal.SortKeys...))
+	listCmd.RegisterFlagCompletionFunc("columns", completeValues(tui.ColumnNames()...))
 	rootCmd.AddCommand(listCmd)
 }
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/trishan9/porty/internal"
+)
+
+var logSince string
+var logUntil string
+var logPort string
+var logProcess string
+
+var logCmd = &cobra.Command{
+	Use:   "log",
+	Short: "Show the audit log of kills made with porty",
+	Long: `Show the audit log of kills made with porty.
+
+Every kill from the CLI or the TUI is appended to
+$XDG_STATE_HOME/porty/kills.log (~/.local/state/porty/kills.log by default).`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var f internal.AuditFilter
+		var err error
+		if f.Since, err = parseLogTime(logSince); err != nil {
+			return fmt.Errorf("invalid --since: %w", err)
+		}
+		if f.Until, err = parseLogTime(logUntil); err != nil {
+			return fmt.Errorf("invalid --until: %w", err)
+		}
+		f.Port = logPort
+		f.Process = logProcess
+
+		records, err := internal.ReadAudit(f)
+		if err != nil {
+			return fmt.Errorf("failed to read audit log: %w", err)
+		}
+
+		if records == nil {
+			records = []internal.AuditRecord{}
+		}
+		return writeOutput(os.Stdout, records, records, func() error {
+			if len(records) == 0 {
+				fmt.Println("no kills recorded")
+				return nil
+			}
+			return printAudit(records)
+		})
+	},
+}
+
+// printAudit renders audit records as a table.
+func printAudit(records []internal.AuditRecord) error {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tUSER\tSOURCE\tPID\tPORT\tPROCESS\tSIGNAL\tOUTCOME\tCMDLINE")
+	for _, r := range records {
+		who := r.User
+		if r.SudoUser != "" {
+			who += " (" + r.SudoUser + ")"
+		}
+		port := r.Port
+		if port == "" {
+			port = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\n",
+			r.Time.Local().Format("2006-01-02 15:04:05"), who, r.Source, r.PID, port,
+			r.Process, r.Signal, r.Outcome, truncateCmdline(r.Cmdline, 60))
+	}
+	return tw.Flush()
+}
+
+// parseLogTime accepts a duration back from now ("2h"), a date, or RFC 3339.
+func parseLogTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	if days, ok := parseDays(s); ok {
+		return time.Now().AddDate(0, 0, -days), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a duration (2h, 7d), date or RFC 3339 time", s)
+}
+
+// parseDays parses "7d", which time.ParseDuration does not know.
+func parseDays(s string) (int, bool) {
+	if len(s) < 2 || s[len(s)-1] != 'd' {
+		return 0, false
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	return n, err == nil && n >= 0
+}
+
+func truncateCmdline(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max-3]) + "..."
+}
+
+func init() {
+	logCmd.Flags().StringVar(&logSince, "since", "", "Only kills after this time (e.g. 2h, 7d, 2025-01-31)")
+	logCmd.Flags().StringVar(&logUntil, "until", "", "Only kills before this time")
+	logCmd.Flags().StringVar(&logPort, "port", "", "Only kills of this port")
+	logCmd.Flags().StringVar(&logProcess, "process", "", "Only kills whose process or cmdline contains this")
+	logCmd.Example = `
+		porty log
+		porty log --since 24h --port 3000
+		porty log --process node --json
+		porty log -o csv > kills.csv
+		`
+	rootCmd.AddCommand(logCmd)
+}
@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+var outputFlag string
+var templateFlag string
+
+// outputFormats are the values --output accepts.
+var outputFormats = []string{"json", "ndjson", "yaml", "csv", "tsv", "table", "template"}
+
+// outputFormat returns the format chosen with --output, or "json" for the
+// older --json flag. "" means the command's default human output.
+func outputFormat() string {
+	if outputFlag != "" {
+		return outputFlag
+	}
+	if jsonOutput {
+		return "json"
+	}
+	if templateFlag != "" {
+		return "template"
+	}
+	return ""
+}
+
+// machineReadable reports whether stdout is meant for another program, in
+// which case nothing but the requested output may be written to it.
+func machineReadable() bool {
+	switch outputFormat() {
+	case "", "table":
+		return false
+	}
+	return true
+}
+
+// infoWriter is where progress notes go: stdout for humans, stderr when
+// stdout carries machine-readable output.
+func infoWriter() io.Writer {
+	if machineReadable() {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// validateOutput checks --output and --template before a command runs.
+func validateOutput() error {
+	f := outputFormat()
+	if f == "" {
+		return nil
+	}
+	known := false
+	for _, o := range outputFormats {
+		if f == o {
+			known = true
+		}
+	}
+	if !known {
+		return fmt.Errorf("unknown --output %q (want one of %s)", f, strings.Join(outputFormats, ", "))
+	}
+	if f == "template" {
+		if templateFlag == "" {
+			return fmt.Errorf("--output template needs --template")
+		}
+		if _, err := template.New("output").Parse(templateFlag); err != nil {
+			return fmt.Errorf("invalid --template: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeOutput renders a command's result in the chosen format. doc is the
+// whole document (json, yaml); rows are its records (ndjson, csv, tsv,
+// template). table renders the human output for "table" and the default.
+func writeOutput[T any](w io.Writer, doc any, rows []T, table func() error) error {
+	switch outputFormat() {
+	case "json":
+		b, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	case "yaml":
+		return writeYAML(w, doc)
+	case "ndjson":
+		enc := json.NewEncoder(w)
+		for _, r := range rows {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "csv":
+		return writeDelimited(w, rows, ',')
+	case "tsv":
+		return writeDelimited(w, rows, '\t')
+	case "template":
+		tmpl, err := template.New("output").Parse(templateFlag)
+		if err != nil {
+			return err
+		}
+		for _, r := range rows {
+			if err := tmpl.Execute(w, r); err != nil {
+				return err
+			}
+			fmt.Fprintln(w)
+		}
+		return nil
+	default:
+		return table()
+	}
+}
+
+// writeYAML renders doc through its JSON encoding, so YAML keys and field
+// order match the JSON output instead of Go field names.
+func writeYAML(w io.Writer, doc any) error {
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(b, &node); err != nil {
+		return err
+	}
+	blockStyle(&node)
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// blockStyle undoes the flow style YAML infers from JSON input.
+func blockStyle(n *yaml.Node) {
+	n.Style &^= yaml.FlowStyle
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!str" {
+		n.Style &^= yaml.DoubleQuotedStyle
+	}
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
+
+// writeDelimited writes rows as CSV or TSV. Columns are the union of the
+// rows' JSON keys in order of appearance; nested values are compact JSON.
+func writeDelimited[T any](w io.Writer, rows []T, sep rune) error {
+	var header []string
+	seen := make(map[string]bool)
+	records := make([]map[string]string, 0, len(rows))
+	for _, r := range rows {
+		keys, values, err := flattenJSON(r)
+		if err != nil {
+			return err
+		}
+		rec := make(map[string]string, len(keys))
+		for i, k := range keys {
+			rec[k] = values[i]
+			if !seen[k] {
+				seen[k] = true
+				header = append(header, k)
+			}
+		}
+		records = append(records, rec)
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Comma = sep
+	if len(header) > 0 {
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+	}
+	for _, rec := range records {
+		line := make([]string, len(header))
+		for i, k := range header {
+			line[i] = rec[k]
+		}
+		if err := cw.Write(line); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// flattenJSON returns the top-level keys and values of v's JSON object,
+// preserving field order.
+func flattenJSON(v any) ([]string, []string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, nil, fmt.Errorf("cannot write %T as a table row", v)
+	}
+	var keys, values []string
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, nil, err
+		}
+		var s string
+		if json.Unmarshal(raw, &s) != nil {
+			s = string(raw)
+			if s == "null" {
+				s = ""
+			}
+		}
+		keys = append(keys, tok.(string))
+		values = append(values, s)
+	}
+	return keys, values, nil
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// isTerminal reports whether f is attached to a terminal.
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// confirm asks a yes/no question on the terminal. It refuses rather than
+// guessing when stdin is not interactive.
+func confirm(question string) (bool, error) {
+	if !isTerminal(os.Stdin) {
+		return false, fmt.Errorf("%s refusing without a terminal; re-run with --yes", question)
+	}
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false, nil
+	}
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
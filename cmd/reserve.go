@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/trishan9/porty/internal"
+)
+
+var reserveElevate bool
+
+var reserveCmd = &cobra.Command{
+	Use:   "reserve [add|remove ports]",
+	Short: "Show or change the ports the kernel keeps out of the ephemeral range",
+	Long: `Show or change ip_local_reserved_ports.
+
+The kernel never picks a reserved port for an outgoing connection, so a
+server on a reserved port inside the ephemeral range cannot lose it to one
+(see porty ephemeral). Changing the list needs root: porty offers to retry
+through --helper (sudo), or does so straight away with --elevate.
+
+The change lasts until reboot; to keep it, add
+net.ipv4.ip_local_reserved_ports to a file in /etc/sysctl.d.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		reserved, err := internal.ReservedPorts()
+		if err != nil {
+			return err
+		}
+		return printReserved(reserved)
+	},
+}
+
+var reserveAddCmd = &cobra.Command{
+	Use:   "add <ports>",
+	Short: "Reserve ports and ranges, e.g. 38080,40000-40010",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return changeReserved(cmd, args[0], internal.AddRanges)
+	},
+}
+
+var reserveRemoveCmd = &cobra.Command{
+	Use:   "remove <ports>",
+	Short: "Stop reserving ports and ranges",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return changeReserved(cmd, args[0], internal.SubtractRanges)
+	},
+}
+
+// changeReserved combines the reserved ports with spec through op and
+// writes the result, through the privilege helper unless porty is root.
+func changeReserved(cmd *cobra.Command, spec string, op func(a, b []internal.PortRange) []internal.PortRange) error {
+	ranges, err := internal.ParsePortRanges(spec)
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+	reserved, err := internal.ReservedPorts()
+	if err != nil {
+		return err
+	}
+	want := op(reserved, ranges)
+
+	if internal.IsRoot() {
+		if err := internal.SetReservedPorts(want); err != nil {
+			return err
+		}
+		if reserved, err = internal.ReservedPorts(); err != nil {
+			return err
+		}
+		return printReserved(reserved)
+	}
+
+	if !reserveElevate {
+		if !isTerminal(os.Stdin) {
+			return fmt.Errorf("changing reserved ports needs root; re-run with --elevate or as root")
+		}
+		ok, err := confirm(fmt.Sprintf("Changing reserved ports needs root. Retry through %s?", helperCmd))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("reserved ports unchanged")
+		}
+	}
+	if reserved, err = internal.ElevatedReserve(helperCmd, want); err != nil {
+		return err
+	}
+	return printReserved(reserved)
+}
+
+func printReserved(reserved []internal.PortRange) error {
+	s := internal.FormatRanges(reserved)
+	return writeOutput(os.Stdout, reservedReport{Reserved: s}, []reservedReport{{Reserved: s}}, func() error {
+		if s == "" {
+			fmt.Println("no reserved ports")
+			return nil
+		}
+		fmt.Println(s)
+		return nil
+	})
+}
+
+// reservedReport is the JSON shape of `porty reserve --json`.
+type reservedReport struct {
+	Reserved string `json:"reserved"`
+}
+
+func init() {
+	reserveCmd.PersistentFlags().BoolVar(&reserveElevate, "elevate", false, "Retry through --helper (sudo) without asking when not root")
+	reserveCmd.Example = `
+		porty reserve
+		sudo porty reserve add 38080,40000-40010
+		porty reserve remove 38080 --elevate
+		`
+	reserveCmd.AddCommand(reserveAddCmd, reserveRemoveCmd)
+	rootCmd.AddCommand(reserveCmd)
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/trishan9/porty/internal"
+)
+
+var restartFreeTimeout time.Duration
+var restartListenTimeout time.Duration
+var restartForce bool
+
+var restartCmd = &cobra.Command{
+	Use:   "restart <port|label>",
+	Short: "Kill the process on a port and start it again",
+	Long: `Kill the process listening on a port and start it again.
+
+Before signalling, porty captures the process's cmdline, working directory
+and environment from /proc. Once the port is free, it relaunches the same
+command detached from porty (output goes to a log file in porty's state
+directory) and waits until the port is listening again.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		showBanner()
+
+		entries := scanPorts()
+		ports, err := resolvePortArgs(entries, args)
+		if err != nil {
+			return err
+		}
+		if len(ports) > 1 {
+			return fmt.Errorf("%q labels several ports (%s); restart one of them", args[0], strings.Join(ports, ", "))
+		}
+		port := ports[0]
+		key, ok := internal.RestartKey(entries, port)
+		if !ok {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("nothing is listening on port %s", port)
+		}
+		if !restartForce {
+			guard := protector(false)
+			for _, e := range entries {
+				if internal.KeyOf(e) == key && guard.Protects(e.PID, e.ProcessName) {
+					cmd.SilenceUsage = true
+					return fmt.Errorf("port %s is held by %s (PID %d): %w", port, e.ProcessName, e.PID, internal.ErrProtected)
+				}
+			}
+		}
+
+		res, err := internal.Restart(entries, key, internal.RestartOptions{
+			FreeTimeout:   restartFreeTimeout,
+			ListenTimeout: restartListenTimeout,
+		})
+		if aerr := internal.AppendAudit(res.Kills, "cli"); aerr != nil {
+			fmt.Fprintln(os.Stderr, "warning:", aerr)
+		}
+
+		if machineReadable() {
+			if werr := writeOutput(os.Stdout, res, []internal.RestartResult{res}, nil); werr != nil {
+				return werr
+			}
+			return err
+		}
+
+		if len(res.Context.Args) > 0 {
+			fmt.Printf("captured PID %d: %s\n  in %s\n\n", res.Context.PID, res.Context.Command(), res.Context.Dir)
+		}
+		printKillResults(os.Stdout, res.Kills)
+		if res.Freed.Port != "" {
+			fmt.Println(res.Freed)
+		}
+		if res.NewPID > 0 {
+			fmt.Printf("relaunched as PID %d (output: %s)\n", res.NewPID, res.LogFile)
+		}
+		if res.Listening != nil {
+			fmt.Printf("port %s listening again (PID %d)\n", port, res.Listening.PID)
+		}
+		return err
+	},
+}
+
+func init() {
+	restartCmd.Flags().DurationVar(&restartFreeTimeout, "kill-timeout", 3*time.Second, "How long to wait for the port to be released")
+	restartCmd.Flags().BoolVarP(&restartForce, "force", "f", false, "Also restart processes the config file protects")
+	restartCmd.Flags().DurationVar(&restartListenTimeout, "timeout", 30*time.Second, "How long to wait for the port to listen again")
+	restartCmd.Example = `
+		porty restart 3000
+		porty restart 8080 --timeout 1m
+		porty restart api-gateway
+		`
+	restartCmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return completePortsOrLabels(cmd, args, toComplete)
+	}
+	rootCmd.AddCommand(restartCmd)
+}
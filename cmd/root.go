@@ -1,14 +1,18 @@
 package cmd
 
 import (
+    "errors"
     "fmt"
     "os"
+    "strings"
 
     "github.com/charmbracelet/lipgloss"
     "github.com/spf13/cobra"
+    "github.com/trishan9/porty/internal"
 )
 
 var jsonOutput bool
+var helperCmd string
 
 var rootCmd = &cobra.Command{
 	Use:   "porty",
@@ -21,7 +25,12 @@ It shows color-coded ports, lets you filter, kill ports, and export port details
 	},
 }
 
+// showBanner prints the banner for humans only: never into a pipe or
+// ahead of machine-readable output.
 func showBanner() {
+	if machineReadable() || !isTerminal(os.Stdout) {
+		return
+	}
 	banner := lipgloss.NewStyle().
     Bold(true).
     Foreground(lipgloss.Color("#7dcfff")).
@@ -39,12 +48,62 @@ var PortyBanner = `
      A modern, and minimal port manager     
 `
 
+// exitError makes a command exit with a specific status instead of 1.
+type exitError struct {
+	code int
+}
+
+func (e *exitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+// scanPorts lists ports with the user's labels and custom tags applied.
+func scanPorts() []internal.PortEntry {
+	entries, _ := internal.ListPorts()
+	decorate(entries)
+	return entries
+}
+
+// decorate applies labels, then the configured custom tags, which may
+// match on labels.
+func decorate(entries []internal.PortEntry) {
+	if labels, err := internal.LoadLabels(); err == nil {
+		internal.ApplyLabels(entries, labels)
+	} else {
+		fmt.Fprintln(os.Stderr, "warning:", err)
+	}
+	internal.ApplyTags(entries, cfg.Tags)
+}
+
+// envOr returns the environment variable key, or def when it is unset.
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
+		var ee *exitError
+		if errors.As(err, &ee) {
+			os.Exit(ee.code)
+		}
 		os.Exit(1)
 	}
 }
 
 func init() {
-	rootCmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "Output in JSON format")
+	rootCmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "Output in JSON format (same as --output json)")
+	rootCmd.PersistentFlags().StringVarP(&outputFlag, "output", "o", "", "Output format: "+strings.Join(outputFormats, "|"))
+	rootCmd.RegisterFlagCompletionFunc("output", completeValues(outputFormats...))
+	rootCmd.PersistentFlags().StringVar(&templateFlag, "template", "", "Go template applied to each record, e.g. '{{.LocalPort}} {{.ProcessName}}'")
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Config file (default $XDG_CONFIG_HOME/porty/config.yaml, or PORTY_CONFIG)")
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(cmd); err != nil {
+			return err
+		}
+		return validateOutput()
+	}
+	rootCmd.PersistentFlags().StringVar(&helperCmd, "helper", envOr("PORTY_HELPER", internal.DefaultHelper), "Command used to re-run porty with privileges")
 }
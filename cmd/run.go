@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/trishan9/porty/internal"
+)
+
+var runPort string
+var runEnv string
+var runRange string
+var runListenTimeout time.Duration
+
+var runCmd = &cobra.Command{
+	Use:   "run [--port N|auto] -- command...",
+	Short: "Start a command on a free port and report when it listens",
+	Long: `Start a command on a free port and report when it listens.
+
+porty picks a free port from --range (--port auto, the default) or checks
+that the requested one is free, and keeps it bound until the command has
+started. The port is exported in $PORT (or the variable named by --env) and
+replaces {port} in the command's arguments.
+
+porty then waits for the command, or a process it started, to listen on the
+port and prints its URL. Ctrl-C reaches the command from the terminal,
+other signals are forwarded to it, and porty exits with its status.
+
+When the requested port is busy, porty names the process holding it and
+offers the next free port; without a terminal it fails instead.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if cmd.ArgsLenAtDash() != 0 {
+			return fmt.Errorf("put the command after --, e.g. porty run -- npm run dev")
+		}
+		if runEnv == "" {
+			return fmt.Errorf("--env must name a variable")
+		}
+		cmd.SilenceUsage = true
+
+		port, held, err := reservePort()
+		if err != nil {
+			return err
+		}
+
+		p := strconv.Itoa(port)
+		argv := make([]string, len(args))
+		for i, a := range args {
+			argv[i] = strings.ReplaceAll(a, "{port}", p)
+		}
+		fmt.Fprintf(os.Stderr, "starting %s on port %s (%s=%s)\n", strings.Join(argv, " "), p, runEnv, p)
+
+		done := make(chan struct{})
+		watched := make(chan struct{})
+		code, err := runChild(argv, []string{runEnv + "=" + p}, func(pid int) {
+			internal.ReleasePorts(held)
+			go func() {
+				defer close(watched)
+				watchListen(port, pid, done)
+			}()
+		})
+		if err != nil {
+			internal.ReleasePorts(held)
+			return fmt.Errorf("failed to start %s: %w", argv[0], err)
+		}
+		close(done)
+		<-watched
+
+		if code != 0 {
+			cmd.SilenceErrors = true
+			return &exitError{code: code}
+		}
+		return nil
+	},
+}
+
+// reservePort binds the port the command will get: the first free one in
+// --range for auto, or the requested one. When that is busy, it offers the
+// next free port above it.
+func reservePort() (int, []io.Closer, error) {
+	if runPort == "auto" || runPort == "" {
+		opts := internal.FreeOptions{Count: 1, Proto: "tcp"}
+		ranges, err := internal.ParsePortRanges(runRange)
+		if err != nil {
+			return 0, nil, err
+		}
+		opts.Ranges = ranges
+		ports, held, err := internal.FindFreePorts(opts)
+		if err != nil {
+			return 0, nil, err
+		}
+		return ports[0], held, nil
+	}
+
+	port, err := strconv.Atoi(runPort)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, nil, fmt.Errorf("invalid --port %q: want a port or auto", runPort)
+	}
+	want := []internal.PortRange{{Lo: port, Hi: port}}
+	if _, held, err := internal.FindFreePorts(internal.FreeOptions{Ranges: want, Count: 1, Proto: "tcp"}); err == nil {
+		return port, held, nil
+	}
+
+	busy := fmt.Sprintf("port %d is in use", port)
+	entries, _ := internal.ListPortsMatching(func(proto string, p int) bool { return proto == "tcp" && p == port })
+	if len(entries) > 0 {
+		decorate(entries)
+		busy += " by " + describeEntry(entries[0])
+	}
+	next := []internal.PortRange{{Lo: port + 1, Hi: 65535}}
+	ports, held, err := internal.FindFreePorts(internal.FreeOptions{Ranges: next, Count: 1, Proto: "tcp"})
+	if err != nil {
+		return 0, nil, fmt.Errorf("%s and no port above it is free", busy)
+	}
+	if !isTerminal(os.Stdin) {
+		internal.ReleasePorts(held)
+		return 0, nil, fmt.Errorf("%s; the next free port is %d (porty run --port %d, or porty kill %d)", busy, ports[0], ports[0], port)
+	}
+	fmt.Fprintln(os.Stderr, busy)
+	ok, _ := confirm(fmt.Sprintf("Use port %d instead?", ports[0]))
+	if !ok {
+		internal.ReleasePorts(held)
+		return 0, nil, fmt.Errorf("%s", busy)
+	}
+	return ports[0], held, nil
+}
+
+// watchListen reports when pid, or a process below it, listens on port,
+// and warns if that takes longer than --listen-timeout. It returns once
+// the port is listening or done is closed.
+func watchListen(port, pid int, done <-chan struct{}) {
+	spec := internal.WaitSpec{Ports: []int{port}, Proto: "tcp", Listening: true, Under: pid, Done: done}
+	results, ok := internal.WaitFor(spec, runListenTimeout, 200*time.Millisecond)
+	if ok {
+		e := results[0].Holder
+		fmt.Fprintf(os.Stderr, "listening on %s (%s)\n", listenURL(*e), describeEntry(*e))
+		return
+	}
+	select {
+	case <-done:
+		return
+	default:
+	}
+	fmt.Fprintf(os.Stderr, "warning: not listening on port %d after %s; is it using $%s?\n", port, runListenTimeout, runEnv)
+	if results, ok := internal.WaitFor(spec, 0, time.Second); ok {
+		e := results[0].Holder
+		fmt.Fprintf(os.Stderr, "listening on %s (%s)\n", listenURL(*e), describeEntry(*e))
+	}
+}
+
+// listenURL is the http URL of a listener, via localhost unless it is bound
+// to one specific non-loopback address.
+func listenURL(e internal.PortEntry) string {
+	host := "localhost"
+	if ip := net.ParseIP(e.LocalAddr); ip != nil && !ip.IsUnspecified() && !ip.IsLoopback() {
+		host = e.LocalAddr
+	}
+	return "http://" + net.JoinHostPort(host, e.LocalPort)
+}
+
+func init() {
+	runCmd.Flags().StringVar(&runPort, "port", "auto", "Port to run on, or auto for the first free one in --range")
+	runCmd.Flags().StringVar(&runEnv, "env", "PORT", "Environment variable the port is exported in")
+	runCmd.Flags().StringVar(&runRange, "range", "3000-", "Ports --port auto picks from")
+	runCmd.Flags().DurationVar(&runListenTimeout, "listen-timeout", time.Minute, "Warn when the command is not listening after this long")
+	runCmd.RegisterFlagCompletionFunc("port", completeValues("auto"))
+	runCmd.Example = `
+		porty run -- npm run dev
+		porty run --port 3000 -- npm run dev
+		porty run --env VITE_PORT -- npx vite
+		porty run -- python3 -m http.server {port}
+		`
+	rootCmd.AddCommand(runCmd)
+}
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/trishan9/porty/internal"
+)
+
+var waitListening string
+var waitFree string
+var waitTimeout time.Duration
+var waitInterval time.Duration
+var waitPID int
+var waitProcess string
+var waitProto string
+var waitConnect bool
+
+// exitWaitTimeout is `porty wait`'s status when --timeout expires.
+const exitWaitTimeout = 2
+
+var waitCmd = &cobra.Command{
+	Use:   "wait (--listening PORTS | --free PORTS)",
+	Short: "Block until ports are listening or free",
+	Long: `Block until every given port is listening, or until every one is free.
+
+Ports are comma-separated. --listening waits for TCP listeners unless
+--proto udp is given; --free waits for both protocols unless --proto
+narrows it. --pid and --process constrain the owner: with --listening the
+listener must match, and with --free only matching holders have to let go.
+--connect additionally requires a TCP connect to the port on loopback to
+succeed, so a server that has bound but not yet started accepting does not
+count.
+
+Exit status is 0 once the condition holds, and 2 when --timeout expires
+(0 waits forever).`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if (waitListening == "") == (waitFree == "") {
+			return fmt.Errorf("give exactly one of --listening or --free")
+		}
+		spec := internal.WaitSpec{
+			Listening: waitListening != "",
+			PID:       waitPID,
+			Owner:     internal.Selector{Name: waitProcess},
+			Proto:     strings.ToLower(waitProto),
+			Connect:   waitConnect,
+		}
+		if spec.Proto != "" && spec.Proto != "tcp" && spec.Proto != "udp" {
+			return fmt.Errorf("invalid --proto %q: want tcp or udp", waitProto)
+		}
+		// A UDP socket on the port says nothing about a server accepting
+		// connections, so --listening means TCP unless told otherwise.
+		if spec.Listening && spec.Proto == "" {
+			spec.Proto = "tcp"
+		}
+		if spec.Connect && (!spec.Listening || spec.Proto == "udp") {
+			return fmt.Errorf("--connect only applies to --listening on tcp")
+		}
+		if waitInterval <= 0 {
+			return fmt.Errorf("--interval must be positive")
+		}
+		ports, err := parsePortList(waitListening + waitFree)
+		if err != nil {
+			return err
+		}
+		spec.Ports = ports
+		if err := spec.Owner.Compile(); err != nil {
+			return err
+		}
+		cmd.SilenceUsage = true
+
+		start := time.Now()
+		results, ok := internal.WaitFor(spec, waitTimeout, waitInterval)
+		took := time.Since(start).Round(time.Millisecond)
+
+		err = writeOutput(os.Stdout, results, results, func() error {
+			for _, r := range results {
+				fmt.Println(describeWait(spec, r, took))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if !ok {
+			cmd.SilenceErrors = true
+			return &exitError{code: exitWaitTimeout}
+		}
+		return nil
+	},
+}
+
+// parsePortList parses comma-separated single ports.
+func parsePortList(s string) ([]int, error) {
+	var ports []int
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 1 || n > 65535 {
+			return nil, fmt.Errorf("invalid port %q", p)
+		}
+		ports = append(ports, n)
+	}
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("no ports given")
+	}
+	return ports, nil
+}
+
+func describeWait(spec internal.WaitSpec, r internal.WaitResult, took time.Duration) string {
+	port := "port " + strconv.Itoa(r.Port)
+	switch {
+	case r.Met && spec.Listening:
+		return fmt.Sprintf("%s listening (%s) after %s", port, describeEntry(*r.Holder), took)
+	case r.Met:
+		return fmt.Sprintf("%s free after %s", port, took)
+	case spec.Listening && r.Holder != nil:
+		return fmt.Sprintf("%s: timed out after %s; bound by %s but not accepting connections", port, took, describeEntry(*r.Holder))
+	case spec.Listening:
+		return fmt.Sprintf("%s: timed out after %s; not listening", port, took)
+	default:
+		return fmt.Sprintf("%s: timed out after %s; still held by %s", port, took, describeEntry(*r.Holder))
+	}
+}
+
+func init() {
+	waitCmd.Flags().StringVar(&waitListening, "listening", "", "Wait until these ports are listening")
+	waitCmd.Flags().StringVar(&waitFree, "free", "", "Wait until these ports are free")
+	waitCmd.Flags().DurationVar(&waitTimeout, "timeout", 30*time.Second, "Give up after this long (0 waits forever)")
+	waitCmd.Flags().DurationVar(&waitInterval, "interval", 200*time.Millisecond, "How often to rescan")
+	waitCmd.Flags().IntVar(&waitPID, "pid", 0, "Only count sockets held by this PID")
+	waitCmd.Flags().StringVar(&waitProcess, "process", "", "Only count sockets held by this process (glob or /regex/)")
+	waitCmd.Flags().StringVar(&waitProto, "proto", "", "Protocol (tcp or udp; default tcp with --listening, both with --free)")
+	waitCmd.Flags().BoolVar(&waitConnect, "connect", false, "Also require a TCP connect on loopback to succeed")
+	waitCmd.RegisterFlagCompletionFunc("listening", completePorts)
+	waitCmd.RegisterFlagCompletionFunc("free", completePorts)
+	waitCmd.RegisterFlagCompletionFunc("pid", completePIDs)
+	waitCmd.RegisterFlagCompletionFunc("process", completeProcesses)
+	waitCmd.RegisterFlagCompletionFunc("proto", completeValues("tcp", "udp"))
+	waitCmd.Example = `
+		porty wait --listening 8080 --connect && curl localhost:8080/health
+		porty wait --free 3000 --timeout 10s && npm run dev
+		porty wait --listening 5432,6379 --timeout 2m
+		porty wait --free 3000 --process node
+		`
+	rootCmd.AddCommand(waitCmd)
+}
@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/trishan9/porty/internal"
+)
+
+// parseWhere compiles a --where expression, pointing at the error on a
+// second line when it does not parse.
+func parseWhere(s string) (*internal.Expr, error) {
+	if s == "" {
+		return nil, nil
+	}
+	x, err := internal.ParseExpr(s)
+	var ee *internal.ExprError
+	if errors.As(err, &ee) {
+		return nil, fmt.Errorf("invalid --where at %w\n%s", ee, ee.Caret())
+	}
+	return x, err
+}
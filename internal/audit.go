@@ -0,0 +1,143 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// AuditRecord is one line of the kill audit log.
+type AuditRecord struct {
+	Time     time.Time   `json:"time"`
+	User     string      `json:"user"`
+	SudoUser string      `json:"sudo_user,omitempty"`
+	Source   string      `json:"source"` // cli / tui
+	PID      int         `json:"pid"`
+	Process  string      `json:"process"`
+	Cmdline  string      `json:"cmdline,omitempty"`
+	Port     string      `json:"port,omitempty"`
+	Signal   string      `json:"signal"`
+	Outcome  KillOutcome `json:"outcome"`
+	Error    string      `json:"error,omitempty"`
+}
+
+// AuditFilter narrows ReadAudit. Zero fields match everything; Process is
+// a case-insensitive substring of the process name or cmdline.
+type AuditFilter struct {
+	Since   time.Time
+	Until   time.Time
+	Port    string
+	Process string
+}
+
+// StateDir returns porty's XDG state directory ($XDG_STATE_HOME/porty,
+// defaulting to ~/.local/state/porty).
+func StateDir() string {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "porty")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "porty")
+	}
+	return filepath.Join(home, ".local", "state", "porty")
+}
+
+// AuditLogPath is the JSON-lines file kill records are appended to.
+func AuditLogPath() string {
+	return filepath.Join(StateDir(), "kills.log")
+}
+
+// AppendAudit writes one record per kill result. source says which
+// frontend ran the kill.
+func AppendAudit(results []KillResult, source string) error {
+	if len(results) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(StateDir(), 0o700); err != nil {
+		return fmt.Errorf("failed to create state dir: %w", err)
+	}
+	f, err := os.OpenFile(AuditLogPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	who := "?"
+	if u, err := user.Current(); err == nil {
+		who = u.Username
+	}
+	now := time.Now()
+
+	var b strings.Builder
+	for _, r := range results {
+		line, err := json.Marshal(AuditRecord{
+			Time:     now,
+			User:     who,
+			SudoUser: os.Getenv("SUDO_USER"),
+			Source:   source,
+			PID:      r.PID,
+			Process:  r.Process,
+			Cmdline:  r.Cmdline,
+			Port:     r.Port,
+			Signal:   r.Signal,
+			Outcome:  r.Outcome,
+			Error:    errString(r.Err),
+		})
+		if err != nil {
+			return err
+		}
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	// One write per operation keeps concurrent porty instances from
+	// interleaving partial lines under O_APPEND.
+	if _, err := f.WriteString(b.String()); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// ReadAudit returns the records matching f, oldest first. A missing log
+// is not an error.
+func ReadAudit(f AuditFilter) ([]AuditRecord, error) {
+	file, err := os.Open(AuditLogPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	proc := strings.ToLower(f.Process)
+	var out []AuditRecord
+	sc := bufio.NewScanner(file)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		var rec AuditRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			continue // tolerate a torn or foreign line
+		}
+		if !f.Since.IsZero() && rec.Time.Before(f.Since) {
+			continue
+		}
+		if !f.Until.IsZero() && rec.Time.After(f.Until) {
+			continue
+		}
+		if f.Port != "" && rec.Port != f.Port {
+			continue
+		}
+		if proc != "" && !strings.Contains(strings.ToLower(rec.Process), proc) &&
+			!strings.Contains(strings.ToLower(rec.Cmdline), proc) {
+			continue
+		}
+		out = append(out, rec)
+	}
+	return out, sc.Err()
+}
@@ -0,0 +1,474 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is porty's user configuration, read from ConfigPath. Every scalar
+// can be overridden by a PORTY_* environment variable named after its key
+// path: refresh_interval is PORTY_REFRESH_INTERVAL, theme.blue is
+// PORTY_THEME_BLUE, and lists such as protected take comma-separated values.
+type Config struct {
+	// RefreshInterval is how often the TUI rescans.
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+	// Helper re-runs porty with privileges, e.g. "sudo" or "doas".
+	Helper string `yaml:"helper"`
+	// Filter is the default filter expression of porty list and the TUI,
+	// used when no filter flag is given.
+	Filter  string       `yaml:"filter"`
+	Sort    string       `yaml:"sort"`
+	Reverse bool         `yaml:"reverse"`
+	Output  OutputConfig `yaml:"output"`
+	Theme   Theme        `yaml:"theme"`
+	Keys    Keys         `yaml:"keys"`
+	// Protected lists process names (globs or /regex/) that are never
+	// killed without --force.
+	Protected []string `yaml:"protected"`
+	// Tags are custom tags; the first rule whose expression matches an
+	// entry replaces its tag.
+	Tags []TagRule `yaml:"tags"`
+}
+
+// OutputConfig holds the defaults of the output flags.
+type OutputConfig struct {
+	Format   string `yaml:"format"`
+	Template string `yaml:"template"`
+	Columns  string `yaml:"columns"`
+}
+
+// Theme is the TUI palette, as "#rrggbb" or ANSI color numbers.
+type Theme struct {
+	Text    string `yaml:"text"`
+	Muted   string `yaml:"muted"`
+	Success string `yaml:"success"`
+	Warn    string `yaml:"warn"`
+	Error   string `yaml:"error"`
+	Blue    string `yaml:"blue"`
+	Cyan    string `yaml:"cyan"`
+	Purple  string `yaml:"purple"`
+}
+
+// Keys binds TUI actions to comma-separated keys, in bubbletea's names
+// ("up", "enter", "ctrl+r", "space", or a single character).
+type Keys struct {
+	Up      string `yaml:"up"`
+	Down    string `yaml:"down"`
+	Select  string `yaml:"select"`
+	Kill    string `yaml:"kill"`
+	Tree    string `yaml:"tree"`
+	Parent  string `yaml:"parent"`
+	Restart string `yaml:"restart"`
+	Elevate string `yaml:"elevate"`
+	Filter  string `yaml:"filter"`
+	Label   string `yaml:"label"`
+	Reload  string `yaml:"reload"`
+	Quit    string `yaml:"quit"`
+}
+
+// TagRule tags the entries matching an expression, e.g.
+// {name: DB, where: "port in 3306,5432,6379"}.
+type TagRule struct {
+	Name  string `yaml:"name"`
+	Where string `yaml:"where"`
+
+	expr *Expr
+}
+
+// DefaultConfig is the configuration porty uses without a config file.
+func DefaultConfig() Config {
+	return Config{
+		RefreshInterval: 2 * time.Second,
+		Helper:          DefaultHelper,
+		Sort:            "port",
+		Theme: Theme{
+			Text:    "#c0caf5",
+			Muted:   "#6b7089",
+			Success: "#9ece6a",
+			Warn:    "#e0af68",
+			Error:   "#f7768e",
+			Blue:    "#7aa2f7",
+			Cyan:    "#7dcfff",
+			Purple:  "#bb9af7",
+		},
+		Keys: Keys{
+			Up:      "up,k",
+			Down:    "down,j",
+			Select:  "space",
+			Kill:    "enter,x",
+			Tree:    "t",
+			Parent:  "p",
+			Restart: "R",
+			Elevate: "E",
+			Filter:  "/",
+			Label:   "L",
+			Reload:  "r",
+			Quit:    "q,esc,ctrl+c",
+		},
+		Protected: []string{},
+		Tags:      []TagRule{},
+	}
+}
+
+// ConfigPath returns the config file to use: override if set, then
+// $PORTY_CONFIG, then $XDG_CONFIG_HOME/porty/config.yaml
+// (~/.config/porty/config.yaml).
+func ConfigPath(override string) string {
+	if override != "" {
+		return override
+	}
+	if p := os.Getenv("PORTY_CONFIG"); p != "" {
+		return p
+	}
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			home = "."
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "porty", "config.yaml")
+}
+
+// LoadConfig reads path over the defaults, applies PORTY_* overrides and
+// validates the result. A missing file is not an error.
+func LoadConfig(path string) (Config, error) {
+	cfg := DefaultConfig()
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return cfg, fmt.Errorf("failed to read config: %w", err)
+	}
+	if len(bytes.TrimSpace(data)) > 0 {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&cfg); err != nil {
+			return cfg, fmt.Errorf("invalid config %s: %w", path, err)
+		}
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return cfg, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return cfg, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+var colorRe = regexp.MustCompile(`^(#[0-9a-fA-F]{3}|#[0-9a-fA-F]{6}|[0-9]{1,3})$`)
+
+// Validate checks every field and compiles the tag expressions.
+func (c *Config) Validate() error {
+	if c.RefreshInterval < 100*time.Millisecond {
+		return fmt.Errorf("refresh_interval must be at least 100ms, got %s", c.RefreshInterval)
+	}
+	if err := SortEntries(nil, c.Sort, false); err != nil {
+		return fmt.Errorf("sort: %w", err)
+	}
+	if c.Filter != "" {
+		if _, err := ParseExpr(c.Filter); err != nil {
+			return fmt.Errorf("filter: %w", err)
+		}
+	}
+
+	for name, v := range c.Theme.colors() {
+		if v != "" && !colorRe.MatchString(v) {
+			return fmt.Errorf("theme.%s: %q is not #rgb, #rrggbb or an ANSI color number", name, v)
+		}
+	}
+
+	bound := make(map[string]string)
+	for action, keys := range c.Keys.Bindings() {
+		if len(keys) == 0 {
+			return fmt.Errorf("keys.%s: no key bound", action)
+		}
+		for _, k := range keys {
+			if other, ok := bound[k]; ok && other != action {
+				return fmt.Errorf("keys.%s: %q is already bound to %s", action, k, other)
+			}
+			bound[k] = action
+		}
+	}
+
+	if _, err := NewProtector(c.Protected); err != nil {
+		return fmt.Errorf("protected: %w", err)
+	}
+	for i := range c.Tags {
+		t := &c.Tags[i]
+		if t.Name == "" {
+			return fmt.Errorf("tags[%d]: name is required", i)
+		}
+		x, err := ParseExpr(t.Where)
+		if err != nil {
+			return fmt.Errorf("tags[%d] (%s): %w", i, t.Name, err)
+		}
+		t.expr = x
+	}
+	return nil
+}
+
+func (t Theme) colors() map[string]string {
+	return map[string]string{
+		"text": t.Text, "muted": t.Muted, "success": t.Success, "warn": t.Warn,
+		"error": t.Error, "blue": t.Blue, "cyan": t.Cyan, "purple": t.Purple,
+	}
+}
+
+// Bindings maps each action to its keys. "space" is returned as " ", the
+// name bubbletea gives the space bar.
+func (k Keys) Bindings() map[string][]string {
+	out := make(map[string][]string)
+	for action, spec := range map[string]string{
+		"up": k.Up, "down": k.Down, "select": k.Select, "kill": k.Kill,
+		"tree": k.Tree, "parent": k.Parent, "restart": k.Restart,
+		"elevate": k.Elevate, "filter": k.Filter, "label": k.Label, "reload": k.Reload, "quit": k.Quit,
+	} {
+		var keys []string
+		for _, key := range strings.Split(spec, ",") {
+			key = strings.TrimSpace(key)
+			if key == "space" {
+				key = " "
+			}
+			if key != "" {
+				keys = append(keys, key)
+			}
+		}
+		out[action] = keys
+	}
+	return out
+}
+
+// ApplyTags retags the entries matching rules; the first match wins.
+func ApplyTags(entries []PortEntry, rules []TagRule) {
+	for i := range entries {
+		for _, r := range rules {
+			if r.expr != nil && r.expr.Match(entries[i]) {
+				entries[i].Tag = r.Name
+				break
+			}
+		}
+	}
+}
+
+// ---------- keys, env and editing ----------
+
+// envName is the PORTY_* variable overriding the dotted config key.
+func envName(key string) string {
+	return "PORTY_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// applyEnv overrides scalar and scalar-list keys from the environment.
+func (c *Config) applyEnv() error {
+	var node yaml.Node
+	if err := node.Encode(c); err != nil {
+		return err
+	}
+	changed := false
+	walkConfig(&node, "", func(key string, n *yaml.Node) {
+		v, ok := os.LookupEnv(envName(key))
+		if !ok {
+			return
+		}
+		setNode(n, v)
+		changed = true
+	})
+	if !changed {
+		return nil
+	}
+	if err := node.Decode(c); err != nil {
+		return fmt.Errorf("invalid PORTY_* override: %w", err)
+	}
+	return nil
+}
+
+// walkConfig calls fn for every settable key under n: scalars, and lists
+// of scalars. Lists of mappings (tags) are only editable in the file.
+func walkConfig(n *yaml.Node, prefix string, fn func(key string, n *yaml.Node)) {
+	if n.Kind == yaml.DocumentNode {
+		walkConfig(n.Content[0], prefix, fn)
+		return
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key := n.Content[i].Value
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		v := n.Content[i+1]
+		switch {
+		case v.Kind == yaml.MappingNode:
+			walkConfig(v, key, fn)
+		case v.Kind == yaml.ScalarNode:
+			fn(key, v)
+		case v.Kind == yaml.SequenceNode && key != "tags":
+			fn(key, v)
+		}
+	}
+}
+
+// setNode replaces n's value with v; a list takes comma-separated items.
+func setNode(n *yaml.Node, v string) {
+	if n.Kind == yaml.SequenceNode {
+		n.Content = nil
+		n.Style = yaml.FlowStyle
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: item})
+			}
+		}
+		return
+	}
+	*n = yaml.Node{Kind: yaml.ScalarNode, Value: v}
+}
+
+// ConfigKeys lists every key ConfigValue and SetConfigValue accept.
+func ConfigKeys() []string {
+	var node yaml.Node
+	_ = node.Encode(DefaultConfig())
+	var keys []string
+	walkConfig(&node, "", func(key string, _ *yaml.Node) { keys = append(keys, key) })
+	return keys
+}
+
+// ConfigValue returns the value of a dotted key, lists comma-separated.
+func ConfigValue(c Config, key string) (string, error) {
+	var node yaml.Node
+	if err := node.Encode(c); err != nil {
+		return "", err
+	}
+	var out string
+	found := false
+	walkConfig(&node, "", func(k string, n *yaml.Node) {
+		if k != key {
+			return
+		}
+		found = true
+		if n.Kind == yaml.SequenceNode {
+			items := make([]string, len(n.Content))
+			for i, item := range n.Content {
+				items[i] = item.Value
+			}
+			out = strings.Join(items, ",")
+			return
+		}
+		out = n.Value
+	})
+	if !found {
+		return "", fmt.Errorf("unknown config key %q (see porty config get)", key)
+	}
+	return out, nil
+}
+
+// SetConfigValue sets a dotted key in the file at path, keeping the rest
+// of the file and its comments, and refuses values that do not validate.
+func SetConfigValue(path, key, value string) error {
+	var known bool
+	for _, k := range ConfigKeys() {
+		known = known || k == key
+	}
+	if !known {
+		return fmt.Errorf("unknown config key %q (see porty config get)", key)
+	}
+
+	var doc yaml.Node
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+
+	var defaults yaml.Node
+	_ = defaults.Encode(DefaultConfig())
+	isList := false
+	walkConfig(&defaults, "", func(k string, n *yaml.Node) {
+		isList = isList || (k == key && n.Kind == yaml.SequenceNode)
+	})
+
+	n := lookupNode(doc.Content[0], strings.Split(key, "."))
+	if isList {
+		n.Kind = yaml.SequenceNode
+	}
+	setNode(n, value)
+
+	cfg := DefaultConfig()
+	if err := doc.Decode(&cfg); err != nil {
+		return fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	return writeConfig(path, buf.Bytes())
+}
+
+// lookupNode finds or creates the node at path under mapping m.
+func lookupNode(m *yaml.Node, path []string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == path[0] {
+			if len(path) == 1 {
+				return m.Content[i+1]
+			}
+			return lookupNode(m.Content[i+1], path[1:])
+		}
+	}
+	k := &yaml.Node{Kind: yaml.ScalarNode, Value: path[0]}
+	v := &yaml.Node{Kind: yaml.ScalarNode}
+	if len(path) > 1 {
+		v.Kind = yaml.MappingNode
+	}
+	m.Content = append(m.Content, k, v)
+	if len(path) == 1 {
+		return v
+	}
+	return lookupNode(v, path[1:])
+}
+
+// WriteDefaultConfig creates path with the defaults, unless it exists.
+func WriteDefaultConfig(path string, force bool) error {
+	if _, err := os.Stat(path); err == nil && !force {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", path)
+	}
+	var buf bytes.Buffer
+	buf.WriteString("# porty configuration. Every key can be overridden with PORTY_<KEY>,\n")
+	buf.WriteString("# e.g. PORTY_REFRESH_INTERVAL=5s or PORTY_THEME_BLUE=#89b4fa.\n")
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(DefaultConfig()); err != nil {
+		return err
+	}
+	buf.WriteString(`# Examples:
+# protected: [sshd, postgres, "/^docker/"]
+# tags:
+#   - name: DB
+#     where: port in 3306,5432,6379
+`)
+	return writeConfig(path, buf.Bytes())
+}
+
+func writeConfig(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
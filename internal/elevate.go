@@ -0,0 +1,227 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// DefaultHelper is the command used to re-run porty with privileges.
+const DefaultHelper = "sudo"
+
+// ElevatedKillRequest is what the unprivileged porty hands to the elevated
+// one. Targets are re-validated before signalling; Sockets are the matched
+// sockets the caller could not attribute, whose owners the elevated scan
+// looks up. Protected and Force are the caller's protected processes and
+// --force: root's own config is not the one the user wrote.
+type ElevatedKillRequest struct {
+	Targets   []KillTarget   `json:"targets,omitempty"`
+	Sockets   []SocketTarget `json:"sockets,omitempty"`
+	Protected []string       `json:"protected,omitempty"`
+	Force     bool           `json:"force,omitempty"`
+}
+
+// SocketTarget is a socket as the caller scanned it. The elevated porty
+// signals its owner only while a socket with the same inode, protocol,
+// address and port exists, so it never widens the caller's selection.
+type SocketTarget struct {
+	Inode string `json:"inode"`
+	Proto string `json:"proto"`
+	Addr  string `json:"addr"`
+	Port  string `json:"port"`
+}
+
+func (s SocketTarget) String() string {
+	return s.Proto + " " + net.JoinHostPort(s.Addr, s.Port)
+}
+
+// IsRoot reports whether porty already runs with root privileges.
+func IsRoot() bool {
+	return os.Geteuid() == 0
+}
+
+// PermissionDenied returns the targets of results that failed with EPERM,
+// bound to the socket and start time of the process first attempted.
+func PermissionDenied(results []KillResult) []KillTarget {
+	var out []KillTarget
+	for _, r := range results {
+		if r.Outcome == OutcomePermissionDenied {
+			out = append(out, KillTarget{PID: r.PID, Port: r.Port, Process: r.Process, Inode: r.Inode, StartTime: r.StartTime})
+		}
+	}
+	return out
+}
+
+// UnattributedPorts returns the ports of entries with no known owner. When
+// porty is not root these are usually sockets of other users.
+func UnattributedPorts(entries []PortEntry) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, e := range entries {
+		if e.PID == 0 && !seen[e.LocalPort] {
+			seen[e.LocalPort] = true
+			out = append(out, e.LocalPort)
+		}
+	}
+	return out
+}
+
+// UnattributedSockets returns the entries with no known owner as socket
+// targets for an elevated kill.
+func UnattributedSockets(entries []PortEntry) []SocketTarget {
+	var out []SocketTarget
+	for _, e := range entries {
+		if e.PID == 0 && e.Inode != "" {
+			out = append(out, SocketTarget{Inode: e.Inode, Proto: e.Proto, Addr: e.LocalAddr, Port: e.LocalPort})
+		}
+	}
+	return out
+}
+
+// ElevatedCommand builds `<helper> <porty> elevated <op>` with payload as
+// its stdin. The caller owns stdout, where the elevated porty writes JSON;
+// stderr is left to the terminal so the helper can prompt for a password.
+func ElevatedCommand(helper, op string, payload any) (*exec.Cmd, *bytes.Buffer, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to locate porty binary: %w", err)
+	}
+	argv := strings.Fields(helper)
+	if len(argv) == 0 {
+		argv = []string{DefaultHelper}
+	}
+	argv = append(argv, exe, "elevated", op)
+
+	in, err := json.Marshal(payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	var out bytes.Buffer
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stdout = &out
+	return cmd, &out, nil
+}
+
+// DecodeElevated parses what an elevated porty wrote to stdout.
+func DecodeElevated(out *bytes.Buffer, runErr error, v any) error {
+	if runErr != nil {
+		return fmt.Errorf("elevated porty failed: %w", runErr)
+	}
+	if err := json.Unmarshal(out.Bytes(), v); err != nil {
+		return fmt.Errorf("unreadable reply from elevated porty: %w", err)
+	}
+	return nil
+}
+
+// ElevatedKill runs req through helper and returns the elevated results.
+func ElevatedKill(helper string, req ElevatedKillRequest) ([]KillResult, error) {
+	cmd, out, err := ElevatedCommand(helper, "kill", req)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stderr = os.Stderr
+	var results []KillResult
+	return results, DecodeElevated(out, cmd.Run(), &results)
+}
+
+// ElevatedScan runs ListPorts through helper, attributing every socket.
+func ElevatedScan(helper string) ([]PortEntry, error) {
+	cmd, out, err := ElevatedCommand(helper, "scan", nil)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stderr = os.Stderr
+	var entries []PortEntry
+	return entries, DecodeElevated(out, cmd.Run(), &entries)
+}
+
+// ElevatedReserve sets ip_local_reserved_ports to reserved through helper
+// and returns the value the kernel kept.
+func ElevatedReserve(helper string, reserved []PortRange) ([]PortRange, error) {
+	cmd, out, err := ElevatedCommand(helper, "reserve", FormatRanges(reserved))
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stderr = os.Stderr
+	var kept string
+	if err := DecodeElevated(out, cmd.Run(), &kept); err != nil {
+		return nil, err
+	}
+	return ParsePortRanges(kept)
+}
+
+// ServeElevatedKill is the elevated side of ElevatedKill. Protected
+// processes are skipped unless req.Force is set.
+func ServeElevatedKill(req ElevatedKillRequest) ([]KillResult, error) {
+	targets := append([]KillTarget(nil), req.Targets...)
+	if len(req.Sockets) > 0 {
+		entries, _ := ListPorts()
+		byInode := make(map[string]PortEntry, len(entries))
+		for _, e := range entries {
+			byInode[e.Inode] = e
+		}
+		for _, s := range req.Sockets {
+			e, ok := byInode[s.Inode]
+			if !ok || e.PID <= 0 || e.Proto != s.Proto || e.LocalAddr != s.Addr || e.LocalPort != s.Port {
+				continue
+			}
+			targets = append(targets, TargetForEntry(e))
+		}
+	}
+	var p Protector
+	if !req.Force {
+		var err error
+		if p, err = NewProtector(req.Protected); err != nil {
+			return nil, err
+		}
+	}
+	allowed, skipped := p.Guard(targets)
+	return append(skipped, KillTargets(allowed)...), nil
+}
+
+// MergeResults replaces results for PIDs that were retried with the retry's
+// outcome and appends results for targets that were new to the retry.
+func MergeResults(results, retried []KillResult) []KillResult {
+	byPID := make(map[int]int, len(results))
+	out := append([]KillResult(nil), results...)
+	for i, r := range out {
+		byPID[r.PID] = i
+	}
+	for _, r := range retried {
+		if i, ok := byPID[r.PID]; ok {
+			out[i] = r
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// Attribute fills in owners of unattributed entries from known, an earlier
+// (typically elevated) scan, matching on socket inode. Inodes are unique
+// while a socket lives, so a match is the same socket.
+func Attribute(entries, known []PortEntry) []PortEntry {
+	byInode := make(map[string]PortEntry)
+	for _, k := range known {
+		if k.PID > 0 && k.Inode != "" {
+			byInode[k.Inode] = k
+		}
+	}
+	if len(byInode) == 0 {
+		return entries
+	}
+	for i, e := range entries {
+		if k, ok := byInode[e.Inode]; ok && e.PID == 0 {
+			entries[i].PID = k.PID
+			entries[i].ProcessName = k.ProcessName
+			entries[i].UserName = k.UserName
+			entries[i].Tag = k.Tag
+		}
+	}
+	return entries
+}
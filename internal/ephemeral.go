@@ -0,0 +1,150 @@
+package internal
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PortRangePath holds the range the kernel picks ephemeral (outgoing)
+// ports from.
+const PortRangePath = "/proc/sys/net/ipv4/ip_local_port_range"
+
+// EphemeralRange reads ip_local_port_range.
+func EphemeralRange() (PortRange, error) {
+	data, err := os.ReadFile(PortRangePath)
+	if err != nil {
+		return PortRange{}, fmt.Errorf("failed to read ephemeral port range: %w", err)
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 {
+		return PortRange{}, fmt.Errorf("unexpected %s: %q", PortRangePath, strings.TrimSpace(string(data)))
+	}
+	lo, err1 := strconv.Atoi(fields[0])
+	hi, err2 := strconv.Atoi(fields[1])
+	if err1 != nil || err2 != nil || lo > hi {
+		return PortRange{}, fmt.Errorf("unexpected %s: %q", PortRangePath, strings.TrimSpace(string(data)))
+	}
+	return PortRange{Lo: lo, Hi: hi}, nil
+}
+
+// markEphemeral flags the TCP listeners inside the ephemeral range and
+// not reserved: an outgoing connection can take their port while they are
+// down, and they then fail to start with EADDRINUSE.
+func markEphemeral(entries []PortEntry) {
+	r, err := EphemeralRange()
+	if err != nil {
+		return
+	}
+	reserved, _ := ReservedPorts()
+	for i := range entries {
+		e := &entries[i]
+		port, _ := strconv.Atoi(e.LocalPort)
+		e.Ephemeral = e.Proto == "tcp" && e.State == "LISTEN" && r.Contains(port) && !inRanges(reserved, port)
+	}
+}
+
+// DestinationUse counts the local ephemeral ports held by TCP connections
+// to one destination. Ports are only unique per destination, so a busy
+// destination runs out long before the whole range does.
+type DestinationUse struct {
+	Destination string  `json:"destination"`
+	Ports       int     `json:"ports"`
+	Percent     float64 `json:"percent"`
+}
+
+// EphemeralUsage counts, per remote address and port, the outgoing TCP
+// sockets (including TIME-WAIT) whose local port lies in r, busiest first.
+// Connections accepted by a listener inside r share the listener's port
+// rather than taking an ephemeral one, so they are not counted.
+func EphemeralUsage(r PortRange) []DestinationUse {
+	raw := readSocketTables()
+	listening := make(map[string]bool)
+	for _, s := range raw {
+		if s.proto == "tcp" && s.state == "LISTEN" {
+			listening[s.localPort] = true
+		}
+	}
+	counts := make(map[string]int)
+	for _, s := range raw {
+		if s.proto != "tcp" || s.state == "LISTEN" || listening[s.localPort] || s.remotePort == "0" {
+			continue
+		}
+		if port, err := strconv.Atoi(s.localPort); err != nil || !r.Contains(port) {
+			continue
+		}
+		counts[net.JoinHostPort(s.remoteAddr, s.remotePort)]++
+	}
+	out := make([]DestinationUse, 0, len(counts))
+	size := float64(r.Hi - r.Lo + 1)
+	for dest, n := range counts {
+		out = append(out, DestinationUse{Destination: dest, Ports: n, Percent: float64(n) * 100 / size})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Ports != out[j].Ports {
+			return out[i].Ports > out[j].Ports
+		}
+		return out[i].Destination < out[j].Destination
+	})
+	return out
+}
+
+// SetReservedPorts writes ip_local_reserved_ports, which needs root.
+func SetReservedPorts(ranges []PortRange) error {
+	if err := os.WriteFile(ReservedPortsPath, []byte(FormatRanges(ranges)+"\n"), 0o644); err != nil {
+		return fmt.Errorf("failed to write reserved ports: %w", err)
+	}
+	return nil
+}
+
+// AddRanges returns the union of a and b, merged and sorted.
+func AddRanges(a, b []PortRange) []PortRange {
+	var set [65536]bool
+	fillRanges(&set, a, true)
+	fillRanges(&set, b, true)
+	return collectRanges(&set)
+}
+
+// SubtractRanges returns the ports of a that are not in b.
+func SubtractRanges(a, b []PortRange) []PortRange {
+	var set [65536]bool
+	fillRanges(&set, a, true)
+	fillRanges(&set, b, false)
+	return collectRanges(&set)
+}
+
+// FormatRanges writes ranges the way ParsePortRanges and the kernel read
+// them: "8080,9000-9005".
+func FormatRanges(ranges []PortRange) string {
+	parts := make([]string, len(ranges))
+	for i, r := range ranges {
+		parts[i] = r.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+func fillRanges(set *[65536]bool, ranges []PortRange, v bool) {
+	for _, r := range ranges {
+		for p := r.Lo; p <= r.Hi; p++ {
+			set[p] = v
+		}
+	}
+}
+
+func collectRanges(set *[65536]bool) []PortRange {
+	var out []PortRange
+	for p := 0; p < len(set); p++ {
+		if !set[p] {
+			continue
+		}
+		lo := p
+		for p+1 < len(set) && set[p+1] {
+			p++
+		}
+		out = append(out, PortRange{Lo: lo, Hi: p})
+	}
+	return out
+}
@@ -0,0 +1,473 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	"os/user"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Expr is a compiled filter expression over PortEntry fields, e.g.
+//
+//	proto == "tcp" && port >= 3000 && port < 4000 && user == me && !(process ~ "docker")
+//
+// Comparisons are field op value with ==, !=, <, <=, >, >= and ~ (regex);
+// "in" tests a numeric field against ranges (port in 3000-3999,8080) or addr
+// against a CIDR, an IP, loopback or wildcard. Terms combine with &&, || and
+// !, and parentheses group them. Adjacent terms are ANDed, and the key:value
+// terms and bare words of ParseSelector are terms too, so every selector
+// string is also an expression.
+type Expr struct {
+	src   string
+	match func(PortEntry) bool
+}
+
+// ExprError is a syntax or type error at a byte offset of the expression.
+type ExprError struct {
+	Src string
+	Pos int
+	Msg string
+}
+
+func (e *ExprError) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Column(), e.Msg)
+}
+
+// Column is the 1-based rune column of the error.
+func (e *ExprError) Column() int {
+	return utf8.RuneCountInString(e.Src[:e.Pos]) + 1
+}
+
+// Caret shows the expression with a caret under the error.
+func (e *ExprError) Caret() string {
+	return "  " + e.Src + "\n  " + strings.Repeat(" ", e.Column()-1) + "^"
+}
+
+// ParseExpr compiles src. Errors are *ExprError.
+func ParseExpr(src string) (*Expr, error) {
+	toks, err := lexExpr(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{src: src, toks: toks}
+	match, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "unexpected %s", t)
+	}
+	return &Expr{src: src, match: match}, nil
+}
+
+// Match reports whether e satisfies x.
+func (x *Expr) Match(e PortEntry) bool {
+	return x.match(e)
+}
+
+func (x *Expr) String() string {
+	return x.src
+}
+
+// ---------- lexer ----------
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type exprToken struct {
+	kind tokKind
+	text string
+	pos  int
+}
+
+func (t exprToken) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return strconv.Quote(t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+var exprOps = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "~", "!"}
+
+func lexExpr(src string) ([]exprToken, error) {
+	var toks []exprToken
+	i := 0
+	for i < len(src) {
+		r, size := utf8.DecodeRuneInString(src[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case r == '(':
+			toks = append(toks, exprToken{tokLParen, "(", i})
+			i++
+		case r == ')':
+			toks = append(toks, exprToken{tokRParen, ")", i})
+			i++
+		case r == '"' || r == '\'':
+			s, n, err := lexString(src[i:])
+			if err != nil {
+				return nil, &ExprError{Src: src, Pos: i, Msg: err.Error()}
+			}
+			toks = append(toks, exprToken{tokString, s, i})
+			i += n
+		default:
+			if op := matchOp(src[i:]); op != "" {
+				toks = append(toks, exprToken{tokOp, op, i})
+				i += len(op)
+				continue
+			}
+			n := lexWord(src[i:])
+			toks = append(toks, exprToken{tokWord, src[i : i+n], i})
+			i += n
+		}
+	}
+	return append(toks, exprToken{tokEOF, "", len(src)}), nil
+}
+
+func matchOp(s string) string {
+	for _, op := range exprOps {
+		if strings.HasPrefix(s, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// lexWord returns the length of the bare word at the start of s. A
+// key:value selector term runs to the next space or unbalanced ")" so its
+// value may hold a glob or /regex/.
+func lexWord(s string) int {
+	n := 0
+	for n < len(s) {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if unicode.IsSpace(r) || strings.ContainsRune("()\"'", r) || matchOp(s[n:]) != "" {
+			break
+		}
+		n += size
+	}
+	key, _, ok := strings.Cut(s[:n], ":")
+	if !ok || !isSelectorKey(key) {
+		return n
+	}
+	depth := 0
+	n = len(key) + 1
+	for n < len(s) {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if unicode.IsSpace(r) || (r == ')' && depth == 0) {
+			break
+		}
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+		n += size
+	}
+	return n
+}
+
+// lexString reads a quoted string: "..." with Go escapes, or '...' taken
+// literally. It returns the value and the bytes consumed.
+func lexString(s string) (string, int, error) {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quote == '"':
+			i++
+		case s[i] == quote:
+			if quote == '\'' {
+				return s[1:i], i + 1, nil
+			}
+			v, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", 0, fmt.Errorf("invalid string %s", s[:i+1])
+			}
+			return v, i + 1, nil
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// ---------- parser ----------
+
+type exprParser struct {
+	src  string
+	toks []exprToken
+	i    int
+}
+
+type matcher = func(PortEntry) bool
+
+func (p *exprParser) peek() exprToken { return p.toks[p.i] }
+
+func (p *exprParser) next() exprToken {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *exprParser) errorf(t exprToken, format string, args ...any) error {
+	return &ExprError{Src: p.src, Pos: t.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *exprParser) parseOr() (matcher, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for t := p.peek(); t.kind == tokOp && t.text == "||"; t = p.peek() {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(e PortEntry) bool { return l(e) || right(e) }
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (matcher, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.kind == tokOp && t.text == "&&" {
+			p.next()
+		} else if !startsTerm(t) {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(e PortEntry) bool { return l(e) && right(e) }
+	}
+}
+
+// isComparison reports whether t is a comparison operator or "in".
+func isComparison(t exprToken) bool {
+	if t.kind == tokWord {
+		return t.text == "in"
+	}
+	return t.kind == tokOp && t.text != "&&" && t.text != "||" && t.text != "!"
+}
+
+// startsTerm reports whether t can begin a term, for implicit AND.
+func startsTerm(t exprToken) bool {
+	return t.kind == tokWord || t.kind == tokLParen || (t.kind == tokOp && t.text == "!")
+}
+
+func (p *exprParser) parseUnary() (matcher, error) {
+	t := p.next()
+	switch {
+	case t.kind == tokOp && t.text == "!":
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(e PortEntry) bool { return !x(e) }, nil
+	case t.kind == tokLParen:
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if c := p.next(); c.kind != tokRParen {
+			return nil, p.errorf(c, "expected \")\" to close the \"(\" at column %d, found %s",
+				(&ExprError{Src: p.src, Pos: t.pos}).Column(), c)
+		}
+		return x, nil
+	case t.kind == tokWord:
+		return p.parseTerm(t)
+	case t.kind == tokEOF:
+		return nil, p.errorf(t, "expected a condition, found end of expression")
+	}
+	return nil, p.errorf(t, "expected a condition, found %s", t)
+}
+
+// parseTerm parses what follows a leading word: a comparison, an "in"
+// test, a key:value selector term or a bare word.
+func (p *exprParser) parseTerm(t exprToken) (matcher, error) {
+	if key, _, ok := strings.Cut(t.text, ":"); ok && isSelectorKey(key) {
+		sel, err := ParseSelector(t.text)
+		if err != nil {
+			return nil, p.errorf(t, "%v", err)
+		}
+		return sel.Match, nil
+	}
+
+	op := p.peek()
+	if !isComparison(op) {
+		// A bare word matches processes whose name or cmdline contains it.
+		sel := Selector{Name: "*" + t.text + "*"}
+		if err := sel.Compile(); err != nil {
+			return nil, p.errorf(t, "%v", err)
+		}
+		return sel.Match, nil
+	}
+
+	field, ok := exprFields[strings.ToLower(t.text)]
+	if !ok {
+		return nil, p.errorf(t, "unknown field %q (want %s)", t.text, strings.Join(exprFieldNames, ", "))
+	}
+	p.next()
+	v := p.next()
+	if v.kind != tokWord && v.kind != tokString {
+		return nil, p.errorf(v, "expected a value after %s, found %s", op, v)
+	}
+	value := v.text
+	if v.kind == tokWord && value == "me" && strings.EqualFold(t.text, "user") {
+		if u, err := user.Current(); err == nil {
+			value = u.Username
+		}
+	}
+
+	m, err := field.compare(op.text, value)
+	if err != nil {
+		return nil, p.errorf(v, "%s %s %s: %v", t.text, op.text, v, err)
+	}
+	return m, nil
+}
+
+// ---------- fields ----------
+
+type exprField struct {
+	num func(PortEntry) int
+	str func(PortEntry) string
+	// fold compares strings case-insensitively.
+	fold bool
+	// addr compares parsed addresses rather than strings.
+	addr bool
+	// slug compares values as Slug does, so "api-gateway" is "API Gateway".
+	slug bool
+}
+
+var exprFields = map[string]exprField{
+	"port":    {num: func(e PortEntry) int { n, _ := strconv.Atoi(e.LocalPort); return n }},
+	"pid":     {num: func(e PortEntry) int { return e.PID }},
+	"inode":   {num: func(e PortEntry) int { n, _ := strconv.Atoi(e.Inode); return n }},
+	"proto":   {str: func(e PortEntry) string { return e.Proto }, fold: true},
+	"state":   {str: func(e PortEntry) string { return e.State }, fold: true},
+	"tag":     {str: func(e PortEntry) string { return e.Tag }, fold: true},
+	"user":    {str: func(e PortEntry) string { return e.UserName }},
+	"process": {str: func(e PortEntry) string { return e.ProcessName }},
+	"addr":    {str: func(e PortEntry) string { return e.LocalAddr }, addr: true},
+	"label":   {str: func(e PortEntry) string { return e.Label }, slug: true},
+	"cmdline": {str: func(e PortEntry) string {
+		if e.PID > 0 {
+			return ReadCmdline(e.PID)
+		}
+		return ""
+	}},
+}
+
+var exprFieldNames = []string{"port", "pid", "inode", "proto", "state", "tag", "user", "process", "addr", "label", "cmdline"}
+
+func (f exprField) compare(op, value string) (matcher, error) {
+	if op == "~" {
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, err
+		}
+		if f.num != nil {
+			return func(e PortEntry) bool { return re.MatchString(strconv.Itoa(f.num(e))) }, nil
+		}
+		return func(e PortEntry) bool { return re.MatchString(f.str(e)) }, nil
+	}
+
+	if f.num != nil {
+		if op == "in" {
+			ranges, err := parseRanges(value, math.MaxInt)
+			if err != nil {
+				return nil, err
+			}
+			return func(e PortEntry) bool {
+				n := f.num(e)
+				for _, r := range ranges {
+					if r.Contains(n) {
+						return true
+					}
+				}
+				return false
+			}, nil
+		}
+		want, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("not a number")
+		}
+		return func(e PortEntry) bool { return compareOrdered(op, f.num(e), want) }, nil
+	}
+
+	if f.addr {
+		return compareAddr(op, value)
+	}
+	if op == "in" {
+		return nil, fmt.Errorf("\"in\" needs port, pid, inode or addr")
+	}
+	if f.slug {
+		value = Slug(value)
+		return func(e PortEntry) bool { return compareOrdered(op, Slug(f.str(e)), value) }, nil
+	}
+	if f.fold {
+		value = strings.ToLower(value)
+		return func(e PortEntry) bool { return compareOrdered(op, strings.ToLower(f.str(e)), value) }, nil
+	}
+	return func(e PortEntry) bool { return compareOrdered(op, f.str(e), value) }, nil
+}
+
+// compareAddr handles addr: ==, != and "in" take an IP, a CIDR, loopback
+// or wildcard and compare parsed addresses, so IPv6 spellings agree.
+func compareAddr(op, value string) (matcher, error) {
+	switch op {
+	case "==", "!=", "in":
+	default:
+		return nil, fmt.Errorf("addr supports ==, !=, in and ~")
+	}
+	sel := Selector{Addr: value}
+	if err := sel.Compile(); err != nil {
+		return nil, err
+	}
+	if op == "!=" {
+		return func(e PortEntry) bool { return !sel.matchAddr(e) }, nil
+	}
+	return sel.matchAddr, nil
+}
+
+func compareOrdered[T int | string](op string, a, b T) bool {
+	switch op {
+	case "==":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
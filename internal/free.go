@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ReservedPortsPath lists the ports the kernel never hands out as
+// ephemeral ports; porty free avoids them too.
+const ReservedPortsPath = "/proc/sys/net/ipv4/ip_local_reserved_ports"
+
+// ReservedPorts reads ip_local_reserved_ports; a missing file means none.
+func ReservedPorts() ([]PortRange, error) {
+	data, err := os.ReadFile(ReservedPortsPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read reserved ports: %w", err)
+	}
+	return ParsePortRanges(strings.TrimSpace(string(data)))
+}
+
+// FreeOptions configures FindFreePorts.
+type FreeOptions struct {
+	// Ranges to pick from, in order; empty means 1024-65535 outside the
+	// ephemeral port range.
+	Ranges []PortRange
+	Count  int
+	// Proto is "tcp" or "udp".
+	Proto string
+	// Addr is the bind address checked, "" for every address.
+	Addr string
+}
+
+// FindFreePorts returns opts.Count ports, lowest first, that nothing listens
+// on, that are not reserved, and that can actually be bound on opts.Addr.
+// The returned sockets still hold the ports; closing them releases them.
+// On error, nothing is held.
+func FindFreePorts(opts FreeOptions) ([]int, []io.Closer, error) {
+	ranges := opts.Ranges
+	if len(ranges) == 0 {
+		ranges = []PortRange{{Lo: 1024, Hi: 65535}}
+		if eph, err := EphemeralRange(); err == nil {
+			ranges = SubtractRanges(ranges, []PortRange{eph})
+		}
+	}
+	if opts.Count < 1 {
+		opts.Count = 1
+	}
+	reserved, err := ReservedPorts()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entries, _ := ListPortsMatching(func(proto string, port int) bool {
+		return proto == opts.Proto && inRanges(ranges, port)
+	})
+	busy := make(map[int]bool, len(entries))
+	for _, e := range entries {
+		port, _ := strconv.Atoi(e.LocalPort)
+		busy[port] = true
+	}
+
+	var ports []int
+	var held []io.Closer
+	for _, r := range ranges {
+		for port := max(r.Lo, 1); port <= r.Hi && len(ports) < opts.Count; port++ {
+			if busy[port] || inRanges(reserved, port) {
+				continue
+			}
+			c, err := bindPort(opts.Proto, opts.Addr, port)
+			if err != nil {
+				continue
+			}
+			busy[port] = true
+			ports = append(ports, port)
+			held = append(held, c)
+		}
+	}
+
+	if len(ports) < opts.Count {
+		ReleasePorts(held)
+		return nil, nil, fmt.Errorf("only %d of %d ports are free in %s", len(ports), opts.Count, FormatRanges(ranges))
+	}
+	return ports, held, nil
+}
+
+// ReleasePorts closes sockets returned by FindFreePorts.
+func ReleasePorts(held []io.Closer) {
+	for _, c := range held {
+		c.Close()
+	}
+}
+
+// bindPort binds proto on addr:port, listening for TCP so other porty
+// scans see the port as taken while it is held.
+func bindPort(proto, addr string, port int) (io.Closer, error) {
+	hostPort := net.JoinHostPort(addr, strconv.Itoa(port))
+	if proto == "udp" {
+		return net.ListenPacket("udp", hostPort)
+	}
+	return net.Listen("tcp", hostPort)
+}
+
+func inRanges(ranges []PortRange, port int) bool {
+	for _, r := range ranges {
+		if r.Contains(port) {
+			return true
+		}
+	}
+	return false
+}
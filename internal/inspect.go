@@ -0,0 +1,244 @@
+package internal
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Inspection is everything porty knows about the sockets on a port, or of
+// a process, and about the processes holding them.
+type Inspection struct {
+	Sockets   []SocketInfo  `json:"sockets"`
+	Processes []ProcessInfo `json:"processes"`
+}
+
+// SocketInfo is a scanned socket plus its address family, kernel queues
+// and, for TCP listeners, the peers of its established connections.
+type SocketInfo struct {
+	PortEntry
+	Family string `json:"family"`
+	// TxQueue and RxQueue are in bytes; for a listener RxQueue is the
+	// accept backlog.
+	TxQueue int      `json:"tx_queue"`
+	RxQueue int      `json:"rx_queue"`
+	Peers   []string `json:"peers,omitempty"`
+}
+
+// ProcessInfo describes a process holding an inspected socket. Unreadable
+// lists the details /proc would not give porty, typically for another
+// user's process.
+type ProcessInfo struct {
+	PID        int        `json:"pid"`
+	Name       string     `json:"process"`
+	User       string     `json:"user"`
+	Cmdline    string     `json:"cmdline"`
+	Cwd        string     `json:"cwd,omitempty"`
+	Exe        string     `json:"exe,omitempty"`
+	ExeDeleted bool       `json:"exe_deleted,omitempty"`
+	PortEnv    []string   `json:"port_env,omitempty"`
+	Origin     Origin     `json:"origin"`
+	FDs        int        `json:"fds"`
+	FDLimit    int        `json:"fd_limit,omitempty"`
+	Started    *time.Time `json:"started,omitempty"`
+	Unreadable []string   `json:"unreadable,omitempty"`
+}
+
+// rawSocket is one line of a /proc/net table, whatever its state.
+type rawSocket struct {
+	proto, family, state   string
+	localAddr, localPort   string
+	remoteAddr, remotePort string
+	tx, rx                 int
+	inode                  string
+}
+
+// Inspect details the sockets among entries that match, and their owners.
+func Inspect(entries []PortEntry, match func(PortEntry) bool) Inspection {
+	raw := readSocketTables()
+	byInode := make(map[string]rawSocket, len(raw))
+	for _, r := range raw {
+		byInode[r.inode] = r
+	}
+
+	in := Inspection{Sockets: []SocketInfo{}, Processes: []ProcessInfo{}}
+	seen := make(map[int]bool)
+	for _, e := range entries {
+		if !match(e) {
+			continue
+		}
+		s := SocketInfo{PortEntry: e}
+		if r, ok := byInode[e.Inode]; ok {
+			s.Family, s.TxQueue, s.RxQueue = r.family, r.tx, r.rx
+			if e.State == "LISTEN" {
+				s.Peers = peers(raw, r)
+			}
+		}
+		in.Sockets = append(in.Sockets, s)
+		if e.PID > 0 && !seen[e.PID] {
+			seen[e.PID] = true
+			in.Processes = append(in.Processes, InspectProcess(e.PID))
+		}
+	}
+	return in
+}
+
+// peers returns the remote ends of the established connections accepted
+// by listener l.
+func peers(raw []rawSocket, l rawSocket) []string {
+	wildcard := net.ParseIP(l.localAddr).IsUnspecified()
+	var out []string
+	for _, r := range raw {
+		if r.proto != "tcp" || r.state != "ESTAB" || r.localPort != l.localPort || r.family != l.family {
+			continue
+		}
+		if !wildcard && r.localAddr != l.localAddr {
+			continue
+		}
+		out = append(out, net.JoinHostPort(r.remoteAddr, r.remotePort))
+	}
+	sort.Strings(out)
+	return out
+}
+
+// InspectProcess reads what /proc shows about pid.
+func InspectProcess(pid int) ProcessInfo {
+	base := filepath.Join("/proc", strconv.Itoa(pid))
+	uname, _ := getUserFromPID(pid)
+	p := ProcessInfo{
+		PID:     pid,
+		Name:    getProcessNameFromPID(pid),
+		User:    uname,
+		Cmdline: ReadCmdline(pid),
+		Origin:  DescribeOrigin(pid),
+	}
+
+	if cwd, err := os.Readlink(filepath.Join(base, "cwd")); err == nil {
+		p.Cwd = cwd
+	} else {
+		p.Unreadable = append(p.Unreadable, "cwd")
+	}
+	if exe, err := os.Readlink(filepath.Join(base, "exe")); err == nil {
+		p.Exe, p.ExeDeleted = strings.CutSuffix(exe, " (deleted)")
+	} else {
+		p.Unreadable = append(p.Unreadable, "exe")
+	}
+	if environ, err := os.ReadFile(filepath.Join(base, "environ")); err == nil {
+		for _, kv := range splitNUL(environ) {
+			if name, _, _ := strings.Cut(kv, "="); isPortVar(name) {
+				p.PortEnv = append(p.PortEnv, kv)
+			}
+		}
+	} else {
+		p.Unreadable = append(p.Unreadable, "environ")
+	}
+	if fds, err := os.ReadDir(filepath.Join(base, "fd")); err == nil {
+		p.FDs = len(fds)
+	} else {
+		p.Unreadable = append(p.Unreadable, "fd")
+	}
+	p.FDLimit = readFDLimit(pid)
+	if t, ok := processStart(pid); ok {
+		p.Started = &t
+	}
+	return p
+}
+
+// isPortVar reports whether an environment variable names a port: PORT,
+// PORTS, or a word ending in PORT such as VITE_PORT or DBPORT, but not
+// REPORT_DIR or SUPPORTED.
+func isPortVar(name string) bool {
+	for _, w := range strings.Split(strings.ToUpper(name), "_") {
+		if w == "PORTS" || strings.HasSuffix(w, "PORT") && w != "REPORT" && w != "SUPPORT" {
+			return true
+		}
+	}
+	return false
+}
+
+// readFDLimit returns the soft "Max open files" limit; 0 when unlimited
+// or unknown.
+func readFDLimit(pid int) int {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "limits"))
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if rest, ok := strings.CutPrefix(line, "Max open files"); ok {
+			fields := strings.Fields(rest)
+			if len(fields) > 0 {
+				n, _ := strconv.Atoi(fields[0])
+				return n
+			}
+		}
+	}
+	return 0
+}
+
+// clockTicks is USER_HZ, which is 100 on every Linux architecture porty
+// builds for.
+const clockTicks = 100
+
+// processStart converts pid's start time, in clock ticks since boot, to a
+// wall-clock time using the boot time in /proc/stat.
+func processStart(pid int) (time.Time, bool) {
+	ticks := readStartTime(pid)
+	if ticks == 0 {
+		return time.Time{}, false
+	}
+	data, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return time.Time{}, false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if rest, ok := strings.CutPrefix(line, "btime "); ok {
+			btime, err := strconv.ParseInt(strings.TrimSpace(rest), 10, 64)
+			if err != nil {
+				return time.Time{}, false
+			}
+			boot := time.Unix(btime, 0)
+			return boot.Add(time.Duration(ticks) * time.Second / clockTicks), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// readSocketTables reads every socket of /proc/net/{tcp,udp}[6].
+func readSocketTables() []rawSocket {
+	var out []rawSocket
+	for _, path := range netFiles {
+		proto := strings.TrimSuffix(filepath.Base(path), "6")
+		family := "ipv4"
+		if strings.HasSuffix(path, "6") {
+			family = "ipv6"
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		sc := bufio.NewScanner(file)
+		sc.Scan() // header
+		for sc.Scan() {
+			fields := strings.Fields(sc.Text())
+			if len(fields) < 10 {
+				continue
+			}
+			r := rawSocket{proto: proto, family: family, state: decodeState(proto, fields[3]), inode: fields[9]}
+			r.localAddr, r.localPort = parseIPPort(fields[1], family == "ipv6")
+			r.remoteAddr, r.remotePort = parseIPPort(fields[2], family == "ipv6")
+			if tx, rx, ok := strings.Cut(fields[4], ":"); ok {
+				t, _ := strconv.ParseInt(tx, 16, 64)
+				x, _ := strconv.ParseInt(rx, 16, 64)
+				r.tx, r.rx = int(t), int(x)
+			}
+			out = append(out, r)
+		}
+		file.Close()
+	}
+	return out
+}
@@ -1,11 +1,14 @@
 package internal
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 )
 
 func ParseCSVInts(s string) []int {
@@ -27,9 +30,29 @@ func ParseCSVInts(s string) []int {
 	return out
 }
 
-// KillByPorts finds PIDs for given ports and kills them. Returns status messages.
-func KillByPorts(entries []PortEntry, ports []string) []string {
-	var pids []int
+// ErrIdentityChanged means a target no longer is the process it was
+// selected as, most likely because its PID was recycled.
+var ErrIdentityChanged = errors.New("target identity changed")
+
+// KillTarget identifies a process as it was when it was selected. Inode and
+// StartTime, when set, are re-checked immediately before signalling.
+type KillTarget struct {
+	PID       int    `json:"pid"`
+	Port      string `json:"port,omitempty"`
+	Process   string `json:"process,omitempty"`
+	Inode     string `json:"inode,omitempty"`
+	StartTime uint64 `json:"start_time,omitempty"`
+}
+
+// KillByPorts finds PIDs for given ports and kills them. Returns one result
+// per signalled process; none when no PID holds the ports.
+func KillByPorts(entries []PortEntry, ports []string) []KillResult {
+	return KillTargets(TargetsForPorts(entries, ports))
+}
+
+// TargetsForPorts returns a target for every socket holding one of ports.
+func TargetsForPorts(entries []PortEntry, ports []string) []KillTarget {
+	var targets []KillTarget
 	for _, port := range ports {
 		port = strings.TrimSpace(port)
 		if port == "" {
@@ -37,45 +60,162 @@ func KillByPorts(entries []PortEntry, ports []string) []string {
 		}
 		for _, e := range entries {
 			if e.LocalPort == port && e.PID > 0 {
-				pids = append(pids, e.PID)
+				targets = append(targets, TargetForEntry(e))
 			}
 		}
 	}
-	if len(pids) == 0 {
-		return []string{"no matching PIDs for given ports"}
+	return targets
+}
+
+// TargetsForEntries returns a socket-bound target for every owned entry.
+func TargetsForEntries(entries []PortEntry) []KillTarget {
+	var targets []KillTarget
+	for _, e := range entries {
+		if e.PID > 0 {
+			targets = append(targets, TargetForEntry(e))
+		}
 	}
-	return KillPIDs(pids)
+	return targets
 }
 
-// KillPIDs sends SIGTERM to each PID (unique). Returns status messages.
-func KillPIDs(pids []int) []string {
-	seen := make(map[int]struct{})
-	var msgs []string
+// TargetForEntry returns a target bound to the socket e was scanned from.
+func TargetForEntry(e PortEntry) KillTarget {
+	return KillTarget{PID: e.PID, Port: e.LocalPort, Process: e.ProcessName, Inode: e.Inode}
+}
 
+// TargetsForPIDs returns a target per PID, annotated with the first port it
+// holds in entries. The targets are not bound to a socket: a PID given
+// explicitly is killed whether or not it still listens.
+func TargetsForPIDs(entries []PortEntry, pids []int) []KillTarget {
+	targets := make([]KillTarget, 0, len(pids))
 	for _, pid := range pids {
-		if pid <= 0 {
+		t := KillTarget{PID: pid, StartTime: readStartTime(pid)}
+		for _, e := range entries {
+			if e.PID == pid {
+				t.Port, t.Process = e.LocalPort, e.ProcessName
+				break
+			}
+		}
+		targets = append(targets, t)
+	}
+	return targets
+}
+
+// PIDsForPorts returns the PIDs holding any of the given ports.
+func PIDsForPorts(entries []PortEntry, ports []string) []int {
+	var pids []int
+	for _, t := range TargetsForPorts(entries, ports) {
+		pids = append(pids, t.PID)
+	}
+	return pids
+}
+
+// KillPlans signals every process of every plan, in plan order.
+func KillPlans(plans []KillPlan) []KillResult {
+	return KillTargets(PlanTargets(plans))
+}
+
+// PlanTargets returns the targets of every plan, in signalling order.
+func PlanTargets(plans []KillPlan) []KillTarget {
+	var targets []KillTarget
+	for _, p := range plans {
+		targets = append(targets, p.Targets()...)
+	}
+	return targets
+}
+
+// KillPIDs sends SIGTERM to each PID (unique).
+func KillPIDs(pids []int) []KillResult {
+	return KillTargets(TargetsForPIDs(nil, pids))
+}
+
+// KillTargets sends SIGTERM to each target (unique by PID), skipping any
+// whose identity changed since it was selected.
+func KillTargets(targets []KillTarget) []KillResult {
+	seen := make(map[int]struct{})
+	var results []KillResult
+
+	for _, t := range targets {
+		if t.PID <= 0 {
 			continue
 		}
-		if _, ok := seen[pid]; ok {
+		if _, ok := seen[t.PID]; ok {
 			continue
 		}
-		seen[pid] = struct{}{}
+		seen[t.PID] = struct{}{}
 
-		prefix := fmt.Sprintf("PID %d:", pid)
-		proc, err := os.FindProcess(pid)
-		if err != nil {
-			msgs = append(msgs, prefix+" "+err.Error())
-			continue
+		if t.Process == "" {
+			t.Process = getProcessNameFromPID(t.PID)
+		}
+		// Read before signalling: the process may be gone afterwards.
+		cmdline := ReadCmdline(t.PID)
+		if t.StartTime == 0 {
+			t.StartTime = readStartTime(t.PID)
 		}
-		if err := proc.Signal(syscall.SIGTERM); err != nil {
-			msgs = append(msgs, prefix+" SIGTERM failed: "+err.Error())
-		} else {
-			msgs = append(msgs, prefix+" terminated")
+		start := time.Now()
+		err := signalTarget(t, syscall.SIGTERM)
+		results = append(results, KillResult{
+			PID:       t.PID,
+			Port:      t.Port,
+			Inode:     t.Inode,
+			Process:   t.Process,
+			Cmdline:   cmdline,
+			Signal:    signalName(syscall.SIGTERM),
+			Outcome:   classifyKillErr(err),
+			Err:       err,
+			Duration:  time.Since(start),
+			StartTime: t.StartTime,
+		})
+	}
+	return results
+}
+
+// validate checks that t.PID still is the process t was selected as.
+func (t KillTarget) validate() error {
+	if t.StartTime != 0 && readStartTime(t.PID) != t.StartTime {
+		return fmt.Errorf("%w: process restarted since it was selected", ErrIdentityChanged)
+	}
+	if t.Inode != "" && !pidOwnsInode(t.PID, t.Inode) {
+		return fmt.Errorf("%w: no longer owns socket inode %s", ErrIdentityChanged, t.Inode)
+	}
+	return nil
+}
+
+// signalByPID validates t and signals it through its PID. A PID recycled
+// between the two steps can still be hit; signalTarget prefers pidfds.
+func signalByPID(t KillTarget, sig syscall.Signal) error {
+	if err := t.validate(); err != nil {
+		return err
+	}
+	proc, err := os.FindProcess(t.PID)
+	if err != nil {
+		return err
+	}
+	return proc.Signal(sig)
+}
+
+// pidOwnsInode reports whether /proc/<pid>/fd still has socket:[inode] open.
+func pidOwnsInode(pid int, inode string) bool {
+	fdDir := filepath.Join("/proc", strconv.Itoa(pid), "fd")
+	fdEntries, err := os.ReadDir(fdDir)
+	if err != nil {
+		return false
+	}
+	want := "socket:[" + inode + "]"
+	for _, fd := range fdEntries {
+		if link, err := os.Readlink(filepath.Join(fdDir, fd.Name())); err == nil && link == want {
+			return true
 		}
 	}
+	return false
+}
 
-	if len(msgs) == 0 {
-		msgs = []string{"no valid PIDs to kill"}
+// readStartTime returns the start time (field 22) of /proc/<pid>/stat.
+func readStartTime(pid int) uint64 {
+	fields := readStatFields(pid)
+	if len(fields) < 20 {
+		return 0
 	}
-	return msgs
+	v, _ := strconv.ParseUint(fields[19], 10, 64)
+	return v
 }
@@ -0,0 +1,157 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Label names a port, or a port while a given process holds it.
+type Label struct {
+	Port string `json:"port"`
+	// Process, when set, limits the label to that process name.
+	Process string `json:"process,omitempty"`
+	Name    string `json:"name"`
+	Note    string `json:"note,omitempty"`
+}
+
+// DataDir returns porty's XDG data directory ($XDG_DATA_HOME/porty,
+// defaulting to ~/.local/share/porty).
+func DataDir() string {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "porty")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "porty")
+	}
+	return filepath.Join(home, ".local", "share", "porty")
+}
+
+// LabelsPath is the JSON file labels are stored in.
+func LabelsPath() string {
+	return filepath.Join(DataDir(), "labels.json")
+}
+
+// LoadLabels reads the stored labels; none is not an error.
+func LoadLabels() ([]Label, error) {
+	data, err := os.ReadFile(LabelsPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read labels: %w", err)
+	}
+	var labels []Label
+	if err := json.Unmarshal(data, &labels); err != nil {
+		return nil, fmt.Errorf("invalid labels file %s: %w", LabelsPath(), err)
+	}
+	return labels, nil
+}
+
+func saveLabels(labels []Label) error {
+	sort.SliceStable(labels, func(i, j int) bool {
+		a, _ := strconv.Atoi(labels[i].Port)
+		b, _ := strconv.Atoi(labels[j].Port)
+		if a != b {
+			return a < b
+		}
+		return labels[i].Process < labels[j].Process
+	})
+	data, err := json.MarshalIndent(labels, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(DataDir(), 0o755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+	if err := os.WriteFile(LabelsPath(), append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write labels: %w", err)
+	}
+	return nil
+}
+
+// SetLabel stores l, replacing the label of the same port and process.
+func SetLabel(l Label) error {
+	if n, err := strconv.Atoi(l.Port); err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("invalid port %q", l.Port)
+	}
+	if Slug(l.Name) == "" {
+		return fmt.Errorf("a label needs a name")
+	}
+	if _, err := ParsePortRanges(l.Name); err == nil {
+		return fmt.Errorf("label %q would be mistaken for a port", l.Name)
+	}
+	labels, err := LoadLabels()
+	if err != nil {
+		return err
+	}
+	for i, old := range labels {
+		if old.Port == l.Port && old.Process == l.Process {
+			labels[i] = l
+			return saveLabels(labels)
+		}
+	}
+	return saveLabels(append(labels, l))
+}
+
+// RemoveLabel deletes the label of port and process, reporting whether
+// there was one.
+func RemoveLabel(port, process string) (bool, error) {
+	labels, err := LoadLabels()
+	if err != nil {
+		return false, err
+	}
+	for i, l := range labels {
+		if l.Port == port && l.Process == process {
+			return true, saveLabels(append(labels[:i], labels[i+1:]...))
+		}
+	}
+	return false, nil
+}
+
+// ApplyLabels sets Label and Note on entries. A label for the entry's
+// process wins over one for the port alone.
+func ApplyLabels(entries []PortEntry, labels []Label) {
+	for i := range entries {
+		e := &entries[i]
+		for _, l := range labels {
+			if l.Port != e.LocalPort {
+				continue
+			}
+			if l.Process == e.ProcessName {
+				e.Label, e.Note = l.Name, l.Note
+				break
+			}
+			if l.Process == "" {
+				e.Label, e.Note = l.Name, l.Note
+			}
+		}
+	}
+}
+
+// FindLabels returns the labels whose name matches name, compared as slugs
+// so "api-gateway" finds "API Gateway".
+func FindLabels(labels []Label, name string) []Label {
+	var out []Label
+	for _, l := range labels {
+		if Slug(l.Name) == Slug(name) {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// Slug lowercases s and joins its words with dashes.
+func Slug(s string) string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, "-")
+}
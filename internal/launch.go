@@ -0,0 +1,262 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// LaunchContext is how a process was started, captured from /proc before
+// it is killed so it can be started again.
+type LaunchContext struct {
+	PID  int      `json:"pid"`
+	Port string   `json:"port,omitempty"`
+	Args []string `json:"args"`
+	Dir  string   `json:"cwd"`
+	Env  []string `json:"-"`
+}
+
+// RestartResult describes each step of Restart. NewPID is 0 when the
+// process was not relaunched; Listening is nil when the port never came back.
+type RestartResult struct {
+	Context   LaunchContext `json:"context"`
+	Kills     []KillResult  `json:"kills"`
+	Freed     PortStatus    `json:"freed"`
+	NewPID    int           `json:"new_pid,omitempty"`
+	LogFile   string        `json:"log_file,omitempty"`
+	Listening *PortEntry    `json:"listening,omitempty"`
+}
+
+// RestartOptions bounds how long Restart waits at each step.
+type RestartOptions struct {
+	FreeTimeout   time.Duration
+	ListenTimeout time.Duration
+}
+
+// CaptureLaunchContext reads /proc/<pid>/{cmdline,cwd,environ}.
+func CaptureLaunchContext(pid int) (LaunchContext, error) {
+	base := filepath.Join("/proc", strconv.Itoa(pid))
+
+	raw, err := os.ReadFile(filepath.Join(base, "cmdline"))
+	if err != nil {
+		return LaunchContext{}, fmt.Errorf("failed to read cmdline of PID %d: %w", pid, err)
+	}
+	args := splitNUL(raw)
+	if len(args) == 0 {
+		return LaunchContext{}, fmt.Errorf("PID %d has no cmdline (kernel thread?)", pid)
+	}
+
+	dir, err := os.Readlink(filepath.Join(base, "cwd"))
+	if err != nil {
+		return LaunchContext{}, fmt.Errorf("failed to read cwd of PID %d: %w", pid, err)
+	}
+
+	rawEnv, err := os.ReadFile(filepath.Join(base, "environ"))
+	if err != nil {
+		return LaunchContext{}, fmt.Errorf("failed to read environment of PID %d: %w", pid, err)
+	}
+
+	return LaunchContext{PID: pid, Args: args, Dir: dir, Env: splitNUL(rawEnv)}, nil
+}
+
+// Command renders the captured arguments as a single line.
+func (c LaunchContext) Command() string {
+	return strings.Join(c.Args, " ")
+}
+
+// Relaunch starts c detached from porty, in its own session, with output
+// appended to logFile. It returns the new PID.
+func Relaunch(c LaunchContext, logFile string) (int, error) {
+	path, err := lookPathIn(c.Args[0], envValue(c.Env, "PATH"), c.Dir)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(logFile), 0o700); err != nil {
+		return 0, fmt.Errorf("failed to create log dir: %w", err)
+	}
+	out, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer out.Close()
+
+	cmd := &exec.Cmd{
+		Path:        path,
+		Args:        c.Args,
+		Dir:         c.Dir,
+		Env:         c.Env,
+		Stdout:      out,
+		Stderr:      out,
+		SysProcAttr: &syscall.SysProcAttr{Setsid: true},
+	}
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("failed to start %s: %w", c.Args[0], err)
+	}
+	pid := cmd.Process.Pid
+	_ = cmd.Process.Release()
+	return pid, nil
+}
+
+// RestartKey picks the port Restart acts on when only a number is given:
+// the TCP listener if there is one, since that is what a relaunched server
+// binds again, and the UDP socket otherwise.
+func RestartKey(entries []PortEntry, port string) (PortKey, bool) {
+	key, found := PortKey{}, false
+	for _, e := range entries {
+		if e.LocalPort != port {
+			continue
+		}
+		if e.Proto == "tcp" {
+			return KeyOf(e), true
+		}
+		key, found = KeyOf(e), true
+	}
+	return key, found
+}
+
+// Restart kills whatever holds key, waits for the port to be released,
+// relaunches the owning process with the same arguments, directory and
+// environment, and waits for the port to listen again. Sockets of the
+// other protocol on the same number are left alone.
+func Restart(entries []PortEntry, key PortKey, opts RestartOptions) (RestartResult, error) {
+	var res RestartResult
+
+	var onPort []PortEntry
+	for _, e := range entries {
+		if KeyOf(e) == key {
+			onPort = append(onPort, e)
+		}
+	}
+	targets := TargetsForEntries(onPort)
+	if len(targets) == 0 {
+		return res, fmt.Errorf("nothing with a known PID is listening on port %s", key)
+	}
+	root, err := launchRoot(targets)
+	if err != nil {
+		return res, err
+	}
+
+	ctx, err := CaptureLaunchContext(root)
+	if err != nil {
+		return res, err
+	}
+	ctx.Port = key.Port
+	res.Context = ctx
+
+	res.Kills = KillTargets(targets)
+	for _, k := range res.Kills {
+		if !k.OK() {
+			return res, fmt.Errorf("not relaunching: %s", k)
+		}
+	}
+
+	for _, st := range WaitPortsFreed(entries, SignalledPorts(onPort, res.Kills), opts.FreeTimeout, 100*time.Millisecond) {
+		res.Freed = st
+		if st.State != PortFreed {
+			return res, fmt.Errorf("not relaunching: %s", st)
+		}
+	}
+
+	res.LogFile = filepath.Join(StateDir(), "restart-"+key.Port+".log")
+	if res.NewPID, err = Relaunch(ctx, res.LogFile); err != nil {
+		return res, err
+	}
+
+	if e, ok := WaitListening(key, opts.ListenTimeout, 200*time.Millisecond); ok {
+		res.Listening = &e
+		return res, nil
+	}
+	return res, fmt.Errorf("relaunched as PID %d but port %s is not listening after %s (see %s)",
+		res.NewPID, key, opts.ListenTimeout, res.LogFile)
+}
+
+// WaitListening polls until something listens on key or timeout expires.
+func WaitListening(key PortKey, timeout, interval time.Duration) (PortEntry, bool) {
+	deadline := time.Now().Add(timeout)
+	for {
+		entries, _ := ListPorts()
+		for _, e := range entries {
+			if KeyOf(e) == key {
+				return e, true
+			}
+		}
+		if time.Now().After(deadline) {
+			return PortEntry{}, false
+		}
+		time.Sleep(interval)
+	}
+}
+
+// launchRoot picks the process to relaunch among the holders of a port:
+// forked workers share their parent's socket, so only a holder whose
+// parent does not hold it too was started from outside.
+func launchRoot(targets []KillTarget) (int, error) {
+	holders := make(map[int]bool)
+	for _, t := range targets {
+		holders[t.PID] = true
+	}
+	var roots []int
+	for pid := range holders {
+		if !holders[readPPID(pid)] {
+			roots = append(roots, pid)
+		}
+	}
+	if len(roots) != 1 {
+		return 0, fmt.Errorf("port is held by %d unrelated processes; restart them individually", len(roots))
+	}
+	return roots[0], nil
+}
+
+// lookPathIn resolves name the way the original process's shell would
+// have: against its own PATH, relative to its own working directory.
+func lookPathIn(name, pathEnv, dir string) (string, error) {
+	if strings.Contains(name, "/") {
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(dir, name)
+		}
+		return name, nil
+	}
+	for _, d := range filepath.SplitList(pathEnv) {
+		if d == "" {
+			d = "."
+		}
+		if !filepath.IsAbs(d) {
+			d = filepath.Join(dir, d)
+		}
+		p := filepath.Join(d, name)
+		if fi, err := os.Stat(p); err == nil && !fi.IsDir() && fi.Mode()&0o111 != 0 {
+			return p, nil
+		}
+	}
+	return "", errors.New(name + ": executable not found in the process's PATH")
+}
+
+func envValue(env []string, key string) string {
+	for _, kv := range env {
+		if k, v, ok := strings.Cut(kv, "="); ok && k == key {
+			return v
+		}
+	}
+	return ""
+}
+
+// splitNUL splits a NUL-terminated /proc list, keeping empty arguments.
+func splitNUL(b []byte) []string {
+	b = bytes.TrimSuffix(b, []byte{0})
+	if len(b) == 0 {
+		return nil
+	}
+	var out []string
+	for _, p := range bytes.Split(b, []byte{0}) {
+		out = append(out, string(p))
+	}
+	return out
+}
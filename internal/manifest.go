@@ -0,0 +1,268 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestName is the file porty doctor looks for, walking up from the
+// working directory.
+const ManifestName = ".porty.yml"
+
+// Expectations of a manifest port.
+const (
+	ExpectFree      = "free"
+	ExpectListening = "listening"
+)
+
+// Manifest declares the ports a project's dev stack needs.
+type Manifest struct {
+	// Path is the file the manifest was read from; its directory is the
+	// project root.
+	Path  string         `yaml:"-" json:"path"`
+	Ports []ManifestPort `yaml:"ports" json:"ports"`
+}
+
+// ManifestPort is one port of a Manifest. Expect is "free" when the port
+// must be free before the stack starts, or "listening" when a service must
+// already be up on it.
+type ManifestPort struct {
+	Port    int    `yaml:"port" json:"port"`
+	Proto   string `yaml:"proto" json:"proto"`
+	Service string `yaml:"service" json:"service,omitempty"`
+	Expect  string `yaml:"expect" json:"expect"`
+	// Process, a glob or /regex/ on the process name or cmdline, is the
+	// service's own process. A listener that does not match it is a
+	// conflict; without it any listener counts as the service.
+	Process string `yaml:"process" json:"process,omitempty"`
+
+	sel Selector
+}
+
+// FindManifest returns the path of the nearest ManifestName in dir or one
+// of its parents.
+func FindManifest(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		path := filepath.Join(dir, ManifestName)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("no %s in this directory or any parent", ManifestName)
+		}
+		dir = parent
+	}
+}
+
+// LoadManifest reads and validates the manifest at path.
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	m := &Manifest{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(m); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+	m.Path, _ = filepath.Abs(path)
+	if err := m.validate(); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Root is the project directory the manifest belongs to.
+func (m *Manifest) Root() string {
+	return filepath.Dir(m.Path)
+}
+
+func (m *Manifest) validate() error {
+	seen := make(map[string]bool)
+	for i := range m.Ports {
+		p := &m.Ports[i]
+		if p.Port < 1 || p.Port > 65535 {
+			return fmt.Errorf("ports[%d]: invalid port %d", i, p.Port)
+		}
+		p.Proto = strings.ToLower(p.Proto)
+		if p.Proto == "" {
+			p.Proto = "tcp"
+		}
+		if p.Proto != "tcp" && p.Proto != "udp" {
+			return fmt.Errorf("ports[%d]: proto must be tcp or udp, got %q", i, p.Proto)
+		}
+		p.Expect = strings.ToLower(p.Expect)
+		if p.Expect == "" {
+			p.Expect = ExpectFree
+		}
+		if p.Expect != ExpectFree && p.Expect != ExpectListening {
+			return fmt.Errorf("ports[%d]: expect must be %s or %s, got %q", i, ExpectFree, ExpectListening, p.Expect)
+		}
+		key := strconv.Itoa(p.Port) + "/" + p.Proto
+		if seen[key] {
+			return fmt.Errorf("ports[%d]: %s is declared twice", i, key)
+		}
+		seen[key] = true
+		p.sel = Selector{Name: p.Process}
+		if err := p.sel.Compile(); err != nil {
+			return fmt.Errorf("ports[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Statuses of a Finding.
+const (
+	FindingOK         = "ok"
+	FindingConflict   = "conflict"
+	FindingMissing    = "missing"
+	FindingUnexpected = "unexpected"
+)
+
+// Finding is the state of one manifest port, or of a listener started
+// from the project that the manifest does not declare.
+type Finding struct {
+	Port    int         `json:"port"`
+	Proto   string      `json:"proto"`
+	Service string      `json:"service,omitempty"`
+	Expect  string      `json:"expect,omitempty"`
+	Status  string      `json:"status"`
+	Message string      `json:"message"`
+	Owners  []PortEntry `json:"owners,omitempty"`
+}
+
+// Diagnose compares m against a scan. Each declared port gets a finding:
+// a "free" port held by anything, or a "listening" port held by a process
+// that does not match its Process pattern, is a conflict; a "listening"
+// port nobody holds is missing. Listeners whose working directory is inside
+// the project root but whose port is not declared are unexpected.
+func Diagnose(m *Manifest, entries []PortEntry) []Finding {
+	declared := make(map[string]bool)
+	var findings []Finding
+	for _, p := range m.Ports {
+		declared[strconv.Itoa(p.Port)+"/"+p.Proto] = true
+		f := Finding{Port: p.Port, Proto: p.Proto, Service: p.Service, Expect: p.Expect}
+		f.Owners = holders(entries, p.Port, p.Proto)
+
+		switch {
+		case p.Expect == ExpectFree && len(f.Owners) == 0:
+			f.Status, f.Message = FindingOK, "free"
+		case p.Expect == ExpectFree:
+			f.Status = FindingConflict
+			f.Message = "taken by " + describeOwners(f.Owners)
+			if m.ownsAny(f.Owners) {
+				f.Message += ", started from this project (a previous run?)"
+			}
+		case len(f.Owners) == 0:
+			f.Status, f.Message = FindingMissing, "nothing listening"
+		default:
+			var unrelated []PortEntry
+			for _, e := range f.Owners {
+				if p.Process != "" && !p.sel.Match(e) {
+					unrelated = append(unrelated, e)
+				}
+			}
+			if len(unrelated) > 0 {
+				f.Status = FindingConflict
+				f.Message = fmt.Sprintf("taken by %s, not %s", describeOwners(unrelated), p.Process)
+			} else {
+				f.Status, f.Message = FindingOK, "listening ("+describeOwners(f.Owners)+")"
+			}
+		}
+		findings = append(findings, f)
+	}
+
+	unexpected := make(map[string]*Finding)
+	var order []string
+	for _, e := range entries {
+		key := e.LocalPort + "/" + e.Proto
+		if declared[key] || e.PID <= 0 || e.Tag == "SELF" || e.State == "UNKNOWN" || !m.owns(e.PID) {
+			continue
+		}
+		if f, ok := unexpected[key]; ok {
+			if !hasPID(f.Owners, e.PID) {
+				f.Owners = append(f.Owners, e)
+			}
+			continue
+		}
+		port, _ := strconv.Atoi(e.LocalPort)
+		unexpected[key] = &Finding{Port: port, Proto: e.Proto, Status: FindingUnexpected, Owners: []PortEntry{e}}
+		order = append(order, key)
+	}
+	for _, key := range order {
+		f := unexpected[key]
+		f.Message = "not in the manifest, held by " + describeOwners(f.Owners)
+		findings = append(findings, *f)
+	}
+	return findings
+}
+
+// holders returns the entries bound to port/proto, one per PID.
+func holders(entries []PortEntry, port int, proto string) []PortEntry {
+	var out []PortEntry
+	for _, e := range entries {
+		if e.LocalPort != strconv.Itoa(port) || e.Proto != proto {
+			continue
+		}
+		if e.PID > 0 && hasPID(out, e.PID) {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+func hasPID(entries []PortEntry, pid int) bool {
+	for _, e := range entries {
+		if e.PID == pid {
+			return true
+		}
+	}
+	return false
+}
+
+// owns reports whether pid runs with its working directory inside the
+// project root.
+func (m *Manifest) owns(pid int) bool {
+	dir, err := os.Readlink(filepath.Join("/proc", strconv.Itoa(pid), "cwd"))
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(m.Root(), dir)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func (m *Manifest) ownsAny(entries []PortEntry) bool {
+	for _, e := range entries {
+		if e.PID > 0 && m.owns(e.PID) {
+			return true
+		}
+	}
+	return false
+}
+
+func describeOwners(entries []PortEntry) string {
+	parts := make([]string, len(entries))
+	for i, e := range entries {
+		if e.PID > 0 {
+			parts[i] = fmt.Sprintf("%s (PID %d, user %s)", e.ProcessName, e.PID, e.UserName)
+		} else {
+			parts[i] = e.ProcessName
+		}
+	}
+	return strings.Join(parts, ", ")
+}
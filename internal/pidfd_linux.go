@@ -0,0 +1,27 @@
+package internal
+
+import (
+	"errors"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// signalTarget signals t through a pidfd, which pins the process: once the
+// target is validated, a recycled PID cannot receive the signal. Kernels
+// without pidfd_open (< 5.3, or blocked by seccomp) fall back to kill(2).
+func signalTarget(t KillTarget, sig syscall.Signal) error {
+	fd, err := unix.PidfdOpen(t.PID, 0)
+	if err != nil {
+		if errors.Is(err, unix.ENOSYS) || errors.Is(err, unix.EPERM) {
+			return signalByPID(t, sig)
+		}
+		return err
+	}
+	defer unix.Close(fd)
+
+	if err := t.validate(); err != nil {
+		return err
+	}
+	return unix.PidfdSendSignal(fd, sig, nil, 0)
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package internal
+
+import "syscall"
+
+// signalTarget falls back to kill(2) where pidfds are unavailable.
+func signalTarget(t KillTarget, sig syscall.Signal) error {
+	return signalByPID(t, sig)
+}
@@ -19,28 +19,50 @@ type PortEntry struct {
 	ProcessName string `json:"process"`
 	UserName    string `json:"user"`
 	Tag         string `json:"tag"` // USER / SYSTEM / UNKNOWN / SELF
+	Inode       string `json:"inode"`
+	// Label and Note come from the user's labels; see ApplyLabels.
+	Label string `json:"label,omitempty"`
+	Note  string `json:"note,omitempty"`
+	// Ephemeral marks a TCP listener inside ip_local_port_range.
+	Ephemeral bool `json:"ephemeral,omitempty"`
+	// Stack is the address family: v4, v6, or dual for an entry merged
+	// from sockets of both; see MergeStacks.
+	Stack string `json:"stack"`
+	// Addrs lists the bind addresses of a merged entry, LocalAddr first.
+	Addrs []string `json:"addrs,omitempty"`
 }
 
 // ListPorts scans /proc for TCP/UDP sockets and maps them to processes.
 func ListPorts() ([]PortEntry, error) {
-	inodeToPID := buildInodePIDMap()
-
-	curUser, _ := user.Current()
-	curUID := ""
-	if curUser != nil {
-		curUID = curUser.Uid
-	}
+	return ListPortsMatching(nil)
+}
 
+// ListPortsMatching is ListPorts restricted to the sockets keep accepts;
+// nil keeps every socket. Only the inodes of kept sockets are looked up in
+// /proc/<pid>/fd, and the walk stops once all are found, so checking a
+// few ports costs far less than a full scan.
+func ListPortsMatching(keep func(proto string, port int) bool) ([]PortEntry, error) {
 	var entries []PortEntry
 
 	// tcp / tcp6
-	entries = append(entries, parseNetFile("/proc/net/tcp", "tcp", inodeToPID, curUID)...)
-	entries = append(entries, parseNetFile("/proc/net/tcp6", "tcp", inodeToPID, curUID)...)
+	entries = append(entries, parseNetFile("/proc/net/tcp", "tcp", keep)...)
+	entries = append(entries, parseNetFile("/proc/net/tcp6", "tcp", keep)...)
 
 	// udp / udp6
-	entries = append(entries, parseNetFile("/proc/net/udp", "udp", inodeToPID, curUID)...)
-	entries = append(entries, parseNetFile("/proc/net/udp6", "udp", inodeToPID, curUID)...)
-
+	entries = append(entries, parseNetFile("/proc/net/udp", "udp", keep)...)
+	entries = append(entries, parseNetFile("/proc/net/udp6", "udp", keep)...)
+
+	var want map[string]bool
+	if keep != nil {
+		want = make(map[string]bool, len(entries))
+		for _, e := range entries {
+			want[e.Inode] = true
+		}
+	}
+	if keep == nil || len(want) > 0 {
+		attributeSockets(entries, buildInodePIDMap(want))
+	}
+	markEphemeral(entries)
 	return entries, nil
 }
 
@@ -48,7 +70,9 @@ func ListPorts() ([]PortEntry, error) {
 // /proc/<pid>/fd -> socket inode -> pid map
 // ------------------------------------------------------------
 
-func buildInodePIDMap() map[string]int {
+// buildInodePIDMap maps socket inodes to the PIDs holding them. With want
+// set, only those inodes are recorded and the walk ends once all are found.
+func buildInodePIDMap(want map[string]bool) map[string]int {
 	result := make(map[string]int)
 
 	procEntries, err := os.ReadDir("/proc")
@@ -80,9 +104,14 @@ func buildInodePIDMap() map[string]int {
 			// socket:[12345]
 			if strings.HasPrefix(link, "socket:[") && strings.HasSuffix(link, "]") {
 				inode := link[len("socket:[") : len(link)-1]
-				result[inode] = pid
+				if want == nil || want[inode] {
+					result[inode] = pid
+				}
 			}
 		}
+		if want != nil && len(result) == len(want) {
+			break
+		}
 	}
 
 	return result
@@ -92,7 +121,9 @@ func buildInodePIDMap() map[string]int {
 // /proc/net/{tcp,udp} parsing
 // ------------------------------------------------------------
 
-func parseNetFile(path, proto string, inodeToPID map[string]int, curUID string) []PortEntry {
+// parseNetFile reads the sockets of one /proc/net file that keep accepts.
+// Entries are not attributed to processes yet; see attributeSockets.
+func parseNetFile(path, proto string, keep func(proto string, port int) bool) []PortEntry {
 	file, err := os.Open(path)
 	if err != nil {
 		return nil
@@ -100,6 +131,10 @@ func parseNetFile(path, proto string, inodeToPID map[string]int, curUID string)
 	defer file.Close()
 
 	isIPv6 := strings.HasSuffix(path, "6")
+	stack := "v4"
+	if isIPv6 {
+		stack = "v6"
+	}
 
 	var entries []PortEntry
 
@@ -134,43 +169,52 @@ func parseNetFile(path, proto string, inodeToPID map[string]int, curUID string)
 			continue
 		}
 
-		pid := inodeToPID[inode]
+		if keep != nil {
+			if port, err := strconv.Atoi(localPort); err != nil || !keep(proto, port) {
+				continue
+			}
+		}
+
+		entries = append(entries, PortEntry{
+			Proto:     proto,
+			State:     state,
+			LocalAddr: localAddr,
+			LocalPort: localPort,
+			Inode:     inode,
+			Stack:     stack,
+		})
+	}
+
+	return entries
+}
+
+// attributeSockets fills in the process, user and tag of each entry.
+func attributeSockets(entries []PortEntry, inodeToPID map[string]int) {
+	curUser, _ := user.Current()
+	curUID := ""
+	if curUser != nil {
+		curUID = curUser.Uid
+	}
+
+	for i := range entries {
+		e := &entries[i]
+		pid := inodeToPID[e.Inode]
 
 		// -------------------------
 		// Kernel-owned sockets:
 		// inode is present but no PID maps to it
 		// -------------------------
 		if pid == 0 {
-			entries = append(entries, PortEntry{
-				Proto:       proto,
-				State:       state,
-				LocalAddr:   localAddr,
-				LocalPort:   localPort,
-				PID:         0,
-				ProcessName: "<kernel>",
-				UserName:    "kernel",
-				Tag:         "KERNEL",
-			})
+			e.ProcessName, e.UserName, e.Tag = "<kernel>", "kernel", "KERNEL"
 			continue
 		}
 
-		pname := getProcessNameFromPID(pid)
 		uname, uid := getUserFromPID(pid)
-		tag := classifyEntry(uid, curUID, pid)
-
-		entries = append(entries, PortEntry{
-			Proto:       proto,
-			State:       state,
-			LocalAddr:   localAddr,
-			LocalPort:   localPort,
-			PID:         pid,
-			ProcessName: pname,
-			UserName:    uname,
-			Tag:         tag,
-		})
+		e.PID = pid
+		e.ProcessName = getProcessNameFromPID(pid)
+		e.UserName = uname
+		e.Tag = classifyEntry(uid, curUID, pid)
 	}
-
-	return entries
 }
 
 // local_field looks like "0100007F:1F90" (IPv4) or "0000000000000000FFFFFFFF00000000:0035" (IPv6)
@@ -351,3 +395,13 @@ func classifyEntry(uid, curUID string, pid int) string {
 	}
 	return "USER"
 }
+
+// ReadCmdline returns /proc/<pid>/cmdline with its NUL separators turned
+// into spaces; empty for kernel threads or unreadable processes.
+func ReadCmdline(pid int) string {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "cmdline"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(strings.ReplaceAll(string(data), "\x00", " "))
+}
@@ -0,0 +1,93 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+)
+
+// ErrProtected means a target matched a protected process pattern.
+var ErrProtected = errors.New("protected process (use --force to kill it)")
+
+// Protector holds process patterns that must not be killed: globs on the
+// process name, or regular expressions wrapped in slashes matched against
+// the name and cmdline.
+type Protector struct {
+	patterns []string
+	globs    []string
+	res      []*regexp.Regexp
+}
+
+// NewProtector compiles patterns.
+func NewProtector(patterns []string) (Protector, error) {
+	p := Protector{patterns: patterns}
+	for _, pat := range patterns {
+		if re, ok := slashRegexp(pat); ok {
+			compiled, err := regexp.Compile(re)
+			if err != nil {
+				return Protector{}, fmt.Errorf("invalid regex %q: %w", pat, err)
+			}
+			p.res = append(p.res, compiled)
+			continue
+		}
+		if _, err := filepath.Match(pat, ""); err != nil {
+			return Protector{}, fmt.Errorf("invalid glob %q: %w", pat, err)
+		}
+		p.globs = append(p.globs, pat)
+	}
+	return p, nil
+}
+
+// Patterns returns the patterns p was compiled from, for handing to an
+// elevated porty.
+func (p Protector) Patterns() []string {
+	return p.patterns
+}
+
+// Protects reports whether the process pid, named process, is protected.
+func (p Protector) Protects(pid int, process string) bool {
+	for _, g := range p.globs {
+		if ok, _ := filepath.Match(g, process); ok {
+			return true
+		}
+	}
+	if len(p.res) == 0 {
+		return false
+	}
+	cmdline := ReadCmdline(pid)
+	for _, re := range p.res {
+		if re.MatchString(process) || (cmdline != "" && re.MatchString(cmdline)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Guard splits targets into those that may be signalled and skipped
+// results for the protected ones.
+func (p Protector) Guard(targets []KillTarget) ([]KillTarget, []KillResult) {
+	if len(p.globs) == 0 && len(p.res) == 0 {
+		return targets, nil
+	}
+	var allowed []KillTarget
+	var skipped []KillResult
+	for _, t := range targets {
+		name := t.Process
+		if name == "" {
+			name = getProcessNameFromPID(t.PID)
+		}
+		if p.Protects(t.PID, name) {
+			skipped = append(skipped, KillResult{
+				PID:     t.PID,
+				Port:    t.Port,
+				Process: name,
+				Outcome: OutcomeSkipped,
+				Err:     ErrProtected,
+			})
+			continue
+		}
+		allowed = append(allowed, t)
+	}
+	return allowed, skipped
+}
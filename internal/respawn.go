@@ -0,0 +1,178 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Origin explains where a process came from: its ancestry, its cgroup and
+// whatever in there is likely to restart it.
+type Origin struct {
+	Chain      []ProcNode `json:"chain"` // the process first, init last
+	Cgroup     string     `json:"cgroup,omitempty"`
+	Unit       string     `json:"unit,omitempty"`
+	UserUnit   bool       `json:"user_unit,omitempty"`
+	Container  string     `json:"container,omitempty"`
+	Supervisor *ProcNode  `json:"supervisor,omitempty"`
+}
+
+// supervisors are programs known to restart their children when they exit.
+var supervisors = map[string]bool{
+	"nodemon": true, "pm2": true, "forever": true, "supervisord": true,
+	"npm": true, "yarn": true, "pnpm": true, "bun": true, "watchexec": true,
+	"entr": true, "air": true, "cargo-watch": true, "reflex": true,
+	"runsv": true, "s6-supervise": true, "gunicorn": true, "uvicorn": true,
+}
+
+var containerRe = regexp.MustCompile(`(?:docker|libpod|cri-containerd|crio)[-/]([0-9a-f]{12,64})`)
+
+// DescribeOrigin walks pid's PPid chain and cgroup.
+func DescribeOrigin(pid int) Origin {
+	var o Origin
+	seen := make(map[int]bool)
+	for cur := pid; cur > 0 && !seen[cur]; cur = readPPID(cur) {
+		seen[cur] = true
+		node := newProcNode(cur)
+		o.Chain = append(o.Chain, *node)
+		if o.Supervisor == nil && cur != pid && isSupervisor(cur) {
+			o.Supervisor = node
+		}
+	}
+
+	o.Cgroup = readCgroup(pid)
+	if m := containerRe.FindStringSubmatch(o.Cgroup); m != nil {
+		o.Container = m[1]
+	}
+	if last := filepath.Base(o.Cgroup); strings.HasSuffix(last, ".service") {
+		o.Unit = last
+		o.UserUnit = strings.Contains(o.Cgroup, "/user@")
+	}
+	return o
+}
+
+// Suggestion names the thing to stop so the port stays free.
+func (o Origin) Suggestion() string {
+	switch {
+	case o.Container != "":
+		id := o.Container
+		if len(id) > 12 {
+			id = id[:12]
+		}
+		return fmt.Sprintf("it runs in container %s; stop it with `docker stop %s`", id, id)
+	case o.Unit != "":
+		flag := ""
+		if o.UserUnit {
+			flag = "--user "
+		}
+		return fmt.Sprintf("it is managed by systemd unit %s; stop it with `systemctl %sstop %s`", o.Unit, flag, o.Unit)
+	case o.Supervisor != nil:
+		return fmt.Sprintf("it is supervised by %s (PID %d); stop it with `porty kill --pid %d --tree`",
+			o.Supervisor.Name, o.Supervisor.PID, o.Supervisor.PID)
+	case len(o.Chain) > 0:
+		if sup, ok := FindSupervisor(o.Chain[0].PID); ok {
+			return fmt.Sprintf("its parent %s (PID %d) may be respawning it; try `porty kill --pid %d --parent`",
+				getProcessNameFromPID(sup), sup, o.Chain[0].PID)
+		}
+	}
+	return "no supervisor found; something else is binding the port"
+}
+
+// ChainString renders the ancestry as "1234 node ← 1200 nodemon ← 1 systemd".
+func (o Origin) ChainString() string {
+	parts := make([]string, 0, len(o.Chain))
+	for _, n := range o.Chain {
+		parts = append(parts, fmt.Sprintf("%d %s", n.PID, n.Name))
+	}
+	return strings.Join(parts, " ← ")
+}
+
+// WatchRespawns keeps watching ports that were freed for window, and
+// reports any that a process not in before rebinds, on the same port and
+// protocol, in that time.
+func WatchRespawns(statuses []PortStatus, before []PortEntry, window, interval time.Duration) []PortStatus {
+	out := append([]PortStatus(nil), statuses...)
+	watching := 0
+	for _, st := range out {
+		if st.State == PortFreed {
+			watching++
+		}
+	}
+	if watching == 0 || window <= 0 {
+		return out
+	}
+
+	held := holdersByKey(before)
+	deadline := time.Now().Add(window)
+	for watching > 0 && time.Now().Before(deadline) {
+		time.Sleep(interval)
+		entries, _ := ListPorts()
+		for i, st := range out {
+			if st.State != PortFreed {
+				continue
+			}
+			for _, e := range entries {
+				if KeyOf(e) == st.PortKey && !held[st.PortKey][e.PID] {
+					out[i] = reboundStatus(st.PortKey, e.PID)
+					watching--
+					break
+				}
+			}
+		}
+	}
+	return out
+}
+
+func reboundStatus(port PortKey, pid int) PortStatus {
+	st := PortStatus{PortKey: port, State: PortRebound, PID: pid, Parent: readPPID(pid)}
+	if pid > 0 {
+		o := DescribeOrigin(pid)
+		st.Origin = &o
+	}
+	return st
+}
+
+func isSupervisor(pid int) bool {
+	if supervisors[strings.ToLower(getProcessNameFromPID(pid))] {
+		return true
+	}
+	cmdline := ReadCmdline(pid)
+	if strings.HasPrefix(cmdline, "PM2 ") {
+		return true
+	}
+	// Interpreted supervisors show up as `node /usr/bin/nodemon ...`.
+	fields := strings.Fields(cmdline)
+	for i := 0; i < len(fields) && i < 2; i++ {
+		if supervisors[strings.ToLower(filepath.Base(fields[i]))] {
+			return true
+		}
+	}
+	return false
+}
+
+// readCgroup returns the unified (v2) cgroup path of pid, or the systemd
+// hierarchy's path on v1 systems.
+func readCgroup(pid int) string {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "cgroup"))
+	if err != nil {
+		return ""
+	}
+	var fallback string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[0] == "0" && parts[1] == "" {
+			return parts[2]
+		}
+		if parts[1] == "name=systemd" || fallback == "" {
+			fallback = parts[2]
+		}
+	}
+	return fallback
+}
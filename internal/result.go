@@ -0,0 +1,165 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// KillOutcome classifies what happened to a single kill target.
+type KillOutcome int
+
+const (
+	OutcomeTerminated KillOutcome = iota
+	OutcomeSkipped
+	OutcomeNotFound
+	OutcomePermissionDenied
+	OutcomeFailed
+)
+
+var outcomeNames = map[KillOutcome]string{
+	OutcomeTerminated:       "terminated",
+	OutcomeSkipped:          "skipped",
+	OutcomeNotFound:         "not_found",
+	OutcomePermissionDenied: "permission_denied",
+	OutcomeFailed:           "failed",
+}
+
+func (o KillOutcome) String() string {
+	if s, ok := outcomeNames[o]; ok {
+		return s
+	}
+	return "unknown"
+}
+
+func (o KillOutcome) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+func (o *KillOutcome) UnmarshalText(b []byte) error {
+	for k, v := range outcomeNames {
+		if v == string(b) {
+			*o = k
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown kill outcome %q", b)
+}
+
+// KillResult is the outcome of signalling one target.
+type KillResult struct {
+	PID      int           `json:"pid"`
+	Port     string        `json:"port,omitempty"`
+	Inode    string        `json:"inode,omitempty"`
+	Process  string        `json:"process"`
+	Cmdline  string        `json:"cmdline,omitempty"`
+	Signal   string        `json:"signal"`
+	Outcome  KillOutcome   `json:"outcome"`
+	Err      error         `json:"-"`
+	Duration time.Duration `json:"-"`
+	// StartTime identifies the process that was signalled, so a retry
+	// cannot hit another process that reused its PID.
+	StartTime uint64 `json:"-"`
+}
+
+// OK reports whether the target was signalled.
+func (r KillResult) OK() bool {
+	return r.Outcome == OutcomeTerminated
+}
+
+func (r KillResult) String() string {
+	prefix := fmt.Sprintf("PID %d:", r.PID)
+	switch r.Outcome {
+	case OutcomeTerminated:
+		return prefix + " terminated"
+	case OutcomeSkipped:
+		return prefix + " skipped, " + errString(r.Err)
+	default:
+		return prefix + " " + r.Signal + " failed: " + errString(r.Err)
+	}
+}
+
+type killResultJSON struct {
+	PID        int         `json:"pid"`
+	Port       string      `json:"port,omitempty"`
+	Process    string      `json:"process"`
+	Cmdline    string      `json:"cmdline,omitempty"`
+	Signal     string      `json:"signal"`
+	Outcome    KillOutcome `json:"outcome"`
+	Error      string      `json:"error,omitempty"`
+	DurationMS float64     `json:"duration_ms"`
+}
+
+func (r KillResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(killResultJSON{
+		PID:        r.PID,
+		Port:       r.Port,
+		Process:    r.Process,
+		Cmdline:    r.Cmdline,
+		Signal:     r.Signal,
+		Outcome:    r.Outcome,
+		Error:      errString(r.Err),
+		DurationMS: float64(r.Duration.Microseconds()) / 1000,
+	})
+}
+
+func (r *KillResult) UnmarshalJSON(b []byte) error {
+	var v killResultJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*r = KillResult{
+		PID:      v.PID,
+		Port:     v.Port,
+		Process:  v.Process,
+		Cmdline:  v.Cmdline,
+		Signal:   v.Signal,
+		Outcome:  v.Outcome,
+		Duration: time.Duration(v.DurationMS * float64(time.Millisecond)),
+	}
+	if v.Error != "" {
+		r.Err = errors.New(v.Error)
+	}
+	return nil
+}
+
+// classifyKillErr maps a signalling error onto an outcome.
+func classifyKillErr(err error) KillOutcome {
+	switch {
+	case err == nil:
+		return OutcomeTerminated
+	case errors.Is(err, ErrIdentityChanged):
+		return OutcomeSkipped
+	case errors.Is(err, syscall.ESRCH), errors.Is(err, os.ErrProcessDone):
+		return OutcomeNotFound
+	case errors.Is(err, syscall.EPERM):
+		return OutcomePermissionDenied
+	default:
+		return OutcomeFailed
+	}
+}
+
+func signalName(sig syscall.Signal) string {
+	switch sig {
+	case syscall.SIGTERM:
+		return "SIGTERM"
+	case syscall.SIGKILL:
+		return "SIGKILL"
+	case syscall.SIGINT:
+		return "SIGINT"
+	case syscall.SIGHUP:
+		return "SIGHUP"
+	default:
+		return fmt.Sprintf("signal %d", int(sig))
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
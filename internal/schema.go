@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Schema is a JSON Schema (draft 2020-12) node. Properties keep the Go
+// field order so the published schema reads like the type it describes.
+type Schema struct {
+	Schema      string     `json:"$schema,omitempty"`
+	Title       string     `json:"title,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Type        string     `json:"type,omitempty"`
+	Format      string     `json:"format,omitempty"`
+	Properties  properties `json:"properties,omitempty"`
+	Required    []string   `json:"required,omitempty"`
+	Items       *Schema    `json:"items,omitempty"`
+	Additional  *Schema    `json:"additionalProperties,omitempty"`
+	Const       any        `json:"const,omitempty"`
+}
+
+type property struct {
+	name   string
+	schema *Schema
+}
+
+type properties []property
+
+func (p properties) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, prop := range p {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, _ := json.Marshal(prop.name)
+		v, err := json.Marshal(prop.schema)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// SnapshotSchema describes the JSON of Snapshot, generated from the Go types.
+func SnapshotSchema() *Schema {
+	s := SchemaFor(reflect.TypeOf(Snapshot{}))
+	s.Schema = "https://json-schema.org/draft/2020-12/schema"
+	s.Title = "porty list"
+	s.Description = "Ports scanned by `porty list --json`."
+	for _, p := range s.Properties {
+		if p.name == "schema_version" {
+			p.schema.Const = SnapshotSchemaVersion
+		}
+	}
+	return s
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// SchemaFor builds the schema of t as encoding/json would marshal it: json
+// tags name properties, "-" fields are left out and fields without
+// omitempty are required.
+func SchemaFor(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: SchemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", Additional: SchemaFor(t.Elem())}
+	case reflect.Struct:
+		s := &Schema{Type: "object"}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" && opts == "" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			s.Properties = append(s.Properties, property{name, SchemaFor(f.Type)})
+			if !strings.Contains(opts, "omitempty") {
+				s.Required = append(s.Required, name)
+			}
+		}
+		return s
+	}
+	return &Schema{}
+}
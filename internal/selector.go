@@ -0,0 +1,334 @@
+package internal
+
+import (
+	"fmt"
+	"net"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// PortRange is an inclusive range of ports; a single port has Lo == Hi.
+type PortRange struct {
+	Lo int
+	Hi int
+}
+
+// Contains reports whether port lies within r.
+func (r PortRange) Contains(port int) bool {
+	return port >= r.Lo && port <= r.Hi
+}
+
+func (r PortRange) String() string {
+	if r.Lo == r.Hi {
+		return strconv.Itoa(r.Lo)
+	}
+	return fmt.Sprintf("%d-%d", r.Lo, r.Hi)
+}
+
+// ParsePortRanges parses a comma-separated list of ports and ranges such
+// as "3000,8000-8999,9000-" (an open end runs to 65535).
+func ParsePortRanges(s string) ([]PortRange, error) {
+	return parseRanges(s, 65535)
+}
+
+// parseRanges parses ParsePortRanges' syntax for numbers up to max.
+func parseRanges(s string, max int) ([]PortRange, error) {
+	var out []PortRange
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lo, hi, isRange := strings.Cut(part, "-")
+		if !isRange {
+			hi = lo
+		}
+		if hi == "" {
+			hi = strconv.Itoa(max)
+		}
+		if lo == "" {
+			lo = "1"
+		}
+		l, err1 := strconv.Atoi(lo)
+		h, err2 := strconv.Atoi(hi)
+		if err1 != nil || err2 != nil || l < 0 || h > max || l > h {
+			if max != 65535 {
+				return nil, fmt.Errorf("invalid number or range %q", part)
+			}
+			return nil, fmt.Errorf("invalid port or range %q", part)
+		}
+		out = append(out, PortRange{Lo: l, Hi: h})
+	}
+	return out, nil
+}
+
+// Selector picks port entries by attribute. Unset fields match anything and
+// set fields combine with AND.
+type Selector struct {
+	Ports []PortRange
+	// Name is a glob, or a regular expression when wrapped in slashes
+	// (/py.*/), matched against the process name and its cmdline.
+	Name string
+	// User is a user name; "me" means the invoking user.
+	User  string
+	Tag   string
+	Proto string
+	// Addr is an IP, a CIDR, "loopback" or "wildcard".
+	Addr  string
+	State string
+	// Where is an expression every entry must also satisfy.
+	Where *Expr
+
+	nameRe  *regexp.Regexp
+	addrNet *net.IPNet
+	addrIP  net.IP
+}
+
+// Empty reports whether s has no criteria at all.
+func (s *Selector) Empty() bool {
+	return len(s.Ports) == 0 && s.Name == "" && s.User == "" &&
+		s.Tag == "" && s.Proto == "" && s.Addr == "" && s.State == "" && s.Where == nil
+}
+
+// Compile validates the patterns in s. It must be called before Match.
+func (s *Selector) Compile() error {
+	if re, ok := slashRegexp(s.Name); ok {
+		compiled, err := regexp.Compile(re)
+		if err != nil {
+			return fmt.Errorf("invalid process regex: %w", err)
+		}
+		s.nameRe = compiled
+	} else if s.Name != "" {
+		if _, err := filepath.Match(s.Name, ""); err != nil {
+			return fmt.Errorf("invalid process glob %q: %w", s.Name, err)
+		}
+	}
+
+	if s.User == "me" {
+		if u, err := user.Current(); err == nil {
+			s.User = u.Username
+		}
+	}
+
+	switch strings.ToLower(s.Addr) {
+	case "", "loopback", "wildcard", "any":
+	default:
+		if _, n, err := net.ParseCIDR(s.Addr); err == nil {
+			s.addrNet = n
+		} else if ip := net.ParseIP(s.Addr); ip != nil {
+			s.addrIP = ip
+		} else {
+			return fmt.Errorf("invalid address %q: want an IP, CIDR, loopback or wildcard", s.Addr)
+		}
+	}
+	return nil
+}
+
+// Match reports whether e satisfies every criterion of s.
+func (s *Selector) Match(e PortEntry) bool {
+	if len(s.Ports) > 0 {
+		port, _ := strconv.Atoi(e.LocalPort)
+		in := false
+		for _, r := range s.Ports {
+			if r.Contains(port) {
+				in = true
+				break
+			}
+		}
+		if !in {
+			return false
+		}
+	}
+	if s.Proto != "" && !strings.EqualFold(s.Proto, e.Proto) {
+		return false
+	}
+	if s.State != "" && !strings.EqualFold(s.State, e.State) {
+		return false
+	}
+	if s.Tag != "" && !strings.EqualFold(s.Tag, e.Tag) {
+		return false
+	}
+	if s.User != "" && s.User != e.UserName {
+		return false
+	}
+	if s.Addr != "" && !s.matchAddr(e) {
+		return false
+	}
+	if s.Name != "" && !s.matchName(e) {
+		return false
+	}
+	if s.Where != nil && !s.Where.Match(e) {
+		return false
+	}
+	return true
+}
+
+// selectorKeys are the keys of the key:value filter syntax, in the order
+// String writes them.
+var selectorKeys = []string{"port", "proto", "state", "addr", "user", "tag", "process"}
+
+func isSelectorKey(key string) bool {
+	switch strings.ToLower(key) {
+	case "port", "proto", "state", "addr", "user", "tag", "process", "name":
+		return true
+	}
+	return false
+}
+
+// ParseSelector parses the filter syntax of the TUI prompt: space-separated
+// key:value terms such as "port:3000-3999 proto:tcp process:node*". A bare
+// word matches processes whose name or cmdline contains it.
+func ParseSelector(q string) (Selector, error) {
+	var s Selector
+	for _, term := range strings.Fields(q) {
+		key, value, ok := strings.Cut(term, ":")
+		if !ok {
+			s.Name = "*" + term + "*"
+			continue
+		}
+		switch strings.ToLower(key) {
+		case "port":
+			ranges, err := ParsePortRanges(value)
+			if err != nil {
+				return Selector{}, err
+			}
+			s.Ports = append(s.Ports, ranges...)
+		case "proto":
+			s.Proto = value
+		case "state":
+			s.State = value
+		case "addr":
+			s.Addr = value
+		case "user":
+			s.User = value
+		case "tag":
+			s.Tag = value
+		case "process", "name":
+			s.Name = value
+		default:
+			return Selector{}, fmt.Errorf("unknown filter %q (want %s)", key, strings.Join(selectorKeys, ", "))
+		}
+	}
+	return s, s.Compile()
+}
+
+// ParseFilter parses a filter typed into the TUI prompt: an expression,
+// which includes plain key:value terms.
+func ParseFilter(q string) (Selector, error) {
+	if strings.TrimSpace(q) == "" {
+		return Selector{}, nil
+	}
+	x, err := ParseExpr(q)
+	if err != nil {
+		return Selector{}, err
+	}
+	return Selector{Where: x}, nil
+}
+
+// String writes s back as a filter ParseFilter reads.
+func (s Selector) String() string {
+	var terms []string
+	if len(s.Ports) > 0 {
+		ranges := make([]string, len(s.Ports))
+		for i, r := range s.Ports {
+			ranges[i] = r.String()
+		}
+		terms = append(terms, "port:"+strings.Join(ranges, ","))
+	}
+	for _, kv := range [][2]string{
+		{"proto", s.Proto}, {"state", s.State}, {"addr", s.Addr},
+		{"user", s.User}, {"tag", s.Tag}, {"process", s.Name},
+	} {
+		if kv[1] != "" {
+			terms = append(terms, kv[0]+":"+kv[1])
+		}
+	}
+	if s.Where != nil {
+		if len(terms) == 0 {
+			return s.Where.String()
+		}
+		terms = append(terms, "("+s.Where.String()+")")
+	}
+	return strings.Join(terms, " ")
+}
+
+// Select returns the entries of entries that match s.
+func (s *Selector) Select(entries []PortEntry) []PortEntry {
+	var out []PortEntry
+	for _, e := range entries {
+		if s.Match(e) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func (s *Selector) matchName(e PortEntry) bool {
+	cmdline := ""
+	if e.PID > 0 {
+		cmdline = ReadCmdline(e.PID)
+	}
+	if s.nameRe != nil {
+		return s.nameRe.MatchString(e.ProcessName) || (cmdline != "" && s.nameRe.MatchString(cmdline))
+	}
+	if ok, _ := filepath.Match(s.Name, e.ProcessName); ok {
+		return true
+	}
+	ok, _ := filepath.Match(s.Name, cmdline)
+	return ok
+}
+
+func (s *Selector) matchAddr(e PortEntry) bool {
+	ip := EntryIP(e)
+	if ip == nil {
+		return false
+	}
+	switch {
+	case strings.EqualFold(s.Addr, "loopback"):
+		return ip.IsLoopback()
+	case strings.EqualFold(s.Addr, "wildcard"), strings.EqualFold(s.Addr, "any"):
+		return ip.IsUnspecified()
+	case s.addrNet != nil:
+		return s.addrNet.Contains(ip)
+	default:
+		return s.addrIP.Equal(ip)
+	}
+}
+
+// slashRegexp unwraps a /regex/ pattern.
+func slashRegexp(p string) (string, bool) {
+	if len(p) >= 2 && strings.HasPrefix(p, "/") && strings.HasSuffix(p, "/") {
+		return p[1 : len(p)-1], true
+	}
+	return "", false
+}
+
+// EntryIP parses e.LocalAddr back into an IP. IPv6 addresses are stored as
+// the eight groups of /proc/net/*6, whose 32-bit words are host-endian, so
+// each word is byte-swapped to get network order.
+func EntryIP(e PortEntry) net.IP {
+	if ip := net.ParseIP(e.LocalAddr); ip != nil && !strings.Contains(e.LocalAddr, ":") {
+		return ip
+	}
+	groups := strings.Split(e.LocalAddr, ":")
+	if len(groups) != 8 {
+		return net.ParseIP(e.LocalAddr)
+	}
+	raw := make([]byte, 16)
+	for i, g := range groups {
+		v, err := strconv.ParseUint(g, 16, 16)
+		if err != nil {
+			return nil
+		}
+		raw[2*i] = byte(v >> 8)
+		raw[2*i+1] = byte(v)
+	}
+	ip := make(net.IP, 16)
+	for w := 0; w < 16; w += 4 {
+		ip[w], ip[w+1], ip[w+2], ip[w+3] = raw[w+3], raw[w+2], raw[w+1], raw[w]
+	}
+	return ip
+}
@@ -0,0 +1,64 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// SnapshotSchemaVersion is bumped whenever a field of Snapshot or PortEntry
+// is renamed, removed or changes meaning. Adding fields does not bump it.
+const SnapshotSchemaVersion = 1
+
+// Snapshot is the versioned envelope of `porty list --json`: the scanned
+// ports plus enough context to tell when, where and by what they were
+// captured.
+type Snapshot struct {
+	SchemaVersion  int         `json:"schema_version"`
+	PortyVersion   string      `json:"porty_version"`
+	Hostname       string      `json:"hostname"`
+	Kernel         string      `json:"kernel"`
+	Timestamp      time.Time   `json:"timestamp"`
+	ScanDurationMS float64     `json:"scan_duration_ms"`
+	Diagnostics    []string    `json:"diagnostics"`
+	Ports          []PortEntry `json:"ports"`
+}
+
+// netFiles are the /proc tables ListPorts reads.
+var netFiles = []string{"/proc/net/tcp", "/proc/net/tcp6", "/proc/net/udp", "/proc/net/udp6"}
+
+// NewSnapshot wraps entries, scanned at start and taking took, in an
+// envelope. Diagnostics note anything that makes the scan incomplete.
+func NewSnapshot(version string, entries []PortEntry, start time.Time, took time.Duration) Snapshot {
+	s := Snapshot{
+		SchemaVersion:  SnapshotSchemaVersion,
+		PortyVersion:   version,
+		Timestamp:      start.UTC(),
+		ScanDurationMS: float64(took.Microseconds()) / 1000,
+		Diagnostics:    []string{},
+		Ports:          entries,
+	}
+	if s.Ports == nil {
+		s.Ports = []PortEntry{}
+	}
+
+	var err error
+	if s.Hostname, err = os.Hostname(); err != nil {
+		s.Diagnostics = append(s.Diagnostics, fmt.Sprintf("hostname unavailable: %v", err))
+	}
+	if b, err := os.ReadFile("/proc/sys/kernel/osrelease"); err == nil {
+		s.Kernel = strings.TrimSpace(string(b))
+	} else {
+		s.Diagnostics = append(s.Diagnostics, fmt.Sprintf("kernel release unavailable: %v", err))
+	}
+	for _, f := range netFiles {
+		if _, err := os.Stat(f); err != nil {
+			s.Diagnostics = append(s.Diagnostics, fmt.Sprintf("%s not scanned: %v", f, err))
+		}
+	}
+	if n := len(UnattributedPorts(entries)); n > 0 && !IsRoot() {
+		s.Diagnostics = append(s.Diagnostics, fmt.Sprintf("%d port(s) have no visible owner; run as root or with --elevate to attribute them", n))
+	}
+	return s
+}
@@ -0,0 +1,49 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SortKeys are the orders SortEntries accepts.
+var SortKeys = []string{"port", "pid", "process", "user"}
+
+// SortEntries orders entries in place by key, ascending unless reverse.
+// Ties fall back to port then protocol so the order is stable across scans.
+func SortEntries(entries []PortEntry, key string, reverse bool) error {
+	var less func(a, b PortEntry) bool
+	switch key {
+	case "", "port":
+		less = func(a, b PortEntry) bool { return false }
+	case "pid":
+		less = func(a, b PortEntry) bool { return a.PID < b.PID }
+	case "process":
+		less = func(a, b PortEntry) bool { return strings.ToLower(a.ProcessName) < strings.ToLower(b.ProcessName) }
+	case "user":
+		less = func(a, b PortEntry) bool { return a.UserName < b.UserName }
+	default:
+		return fmt.Errorf("unknown sort key %q (want %s)", key, strings.Join(SortKeys, ", "))
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if reverse {
+			a, b = b, a
+		}
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		pa, _ := strconv.Atoi(a.LocalPort)
+		pb, _ := strconv.Atoi(b.LocalPort)
+		if pa != pb {
+			return pa < pb
+		}
+		return a.Proto < b.Proto
+	})
+	return nil
+}
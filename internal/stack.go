@@ -0,0 +1,47 @@
+package internal
+
+import "fmt"
+
+// MergeStacks folds the sockets a process holds on one port and protocol
+// into a single entry. /proc lists IPv4 and IPv6 sockets separately, so a
+// server bound to both 0.0.0.0:3000 and [::]:3000 would otherwise show as
+// two rows. The merged entry keeps the first socket's fields, lists every
+// bind address in Addrs and has Stack "dual" when both families are
+// present. Sockets with no known owner are left alone, since two of them
+// on one port may belong to different processes. Order is kept.
+func MergeStacks(entries []PortEntry) []PortEntry {
+	out := make([]PortEntry, 0, len(entries))
+	index := make(map[string]int)
+	for _, e := range entries {
+		e.Addrs = []string{e.LocalAddr}
+		if e.PID <= 0 {
+			out = append(out, e)
+			continue
+		}
+		key := fmt.Sprintf("%d/%s/%s", e.PID, e.LocalPort, e.Proto)
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(out)
+			out = append(out, e)
+			continue
+		}
+		m := &out[i]
+		if !contains(m.Addrs, e.LocalAddr) {
+			m.Addrs = append(m.Addrs, e.LocalAddr)
+		}
+		if m.Stack != e.Stack {
+			m.Stack = "dual"
+		}
+		m.Ephemeral = m.Ephemeral || e.Ephemeral
+	}
+	return out
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,240 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ProcNode is one process in a process tree snapshot.
+type ProcNode struct {
+	PID       int         `json:"pid"`
+	PPID      int         `json:"ppid"`
+	Name      string      `json:"process"`
+	StartTime uint64      `json:"-"`
+	Children  []*ProcNode `json:"children,omitempty"`
+}
+
+// KillPlan is the set of processes a single kill request will signal.
+// Supervisor is 0 when none was requested or none could be found.
+type KillPlan struct {
+	Target     int
+	Supervisor int
+	Root       *ProcNode
+}
+
+// shells are skipped when climbing to a target's supervisor: they only
+// exist to run the real parent (`sh -c "node server.js"` under npm).
+var shells = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "fish": true, "dash": true,
+	"ksh": true, "mksh": true, "tcsh": true, "csh": true, "ash": true,
+}
+
+// PlanKill works out which processes to signal for pid.
+// With parent set, the nearest non-shell ancestor becomes the root.
+// With tree set, every descendant of the root is included.
+func PlanKill(pid int, tree, parent bool) KillPlan {
+	plan := KillPlan{Target: pid}
+	root := pid
+	if parent {
+		if sup, ok := FindSupervisor(pid); ok {
+			plan.Supervisor = sup
+			root = sup
+		}
+	}
+
+	switch {
+	case tree:
+		plan.Root = ProcessTree(root)
+	case plan.Supervisor > 0:
+		plan.Root = newProcNode(plan.Supervisor)
+		plan.Root.Children = []*ProcNode{newProcNode(pid)}
+	default:
+		plan.Root = newProcNode(pid)
+	}
+	return plan
+}
+
+// Targets returns the plan's processes top-down, so a supervisor is
+// signalled before the children it would otherwise respawn. Each target is
+// pinned to the start time it had when the plan was made.
+func (p KillPlan) Targets() []KillTarget {
+	if p.Root == nil {
+		return nil
+	}
+	var out []KillTarget
+	queue := []*ProcNode{p.Root}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		out = append(out, KillTarget{PID: cur.PID, StartTime: cur.StartTime})
+		queue = append(queue, cur.Children...)
+	}
+	return out
+}
+
+// Render draws the plan as an indented tree, marking the original target.
+func (p KillPlan) Render() string {
+	if p.Root == nil {
+		return ""
+	}
+	var b strings.Builder
+	p.Root.render(&b, "", "", p.Target)
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func (n *ProcNode) render(b *strings.Builder, prefix, childPrefix string, target int) {
+	mark := ""
+	if n.PID == target {
+		mark = "  ← target"
+	}
+	fmt.Fprintf(b, "%s%d %s%s\n", prefix, n.PID, n.Name, mark)
+	for i, c := range n.Children {
+		if i == len(n.Children)-1 {
+			c.render(b, childPrefix+"└─ ", childPrefix+"   ", target)
+		} else {
+			c.render(b, childPrefix+"├─ ", childPrefix+"│  ", target)
+		}
+	}
+}
+
+// ProcessTree snapshots pid and all of its descendants.
+func ProcessTree(pid int) *ProcNode {
+	children := buildChildrenMap()
+
+	var walk func(pid int, seen map[int]bool) *ProcNode
+	walk = func(pid int, seen map[int]bool) *ProcNode {
+		seen[pid] = true
+		node := newProcNode(pid)
+		for _, c := range children[pid] {
+			if seen[c] {
+				continue
+			}
+			node.Children = append(node.Children, walk(c, seen))
+		}
+		return node
+	}
+	return walk(pid, make(map[int]bool))
+}
+
+// FindSupervisor climbs from pid to its nearest non-shell ancestor.
+//
+// The climb never leaves pid's session, so a server started straight from
+// an interactive shell does not resolve to the terminal emulator above it.
+// init and porty's own ancestors are never returned.
+func FindSupervisor(pid int) (int, bool) {
+	session := readSession(pid)
+	own := ownAncestors()
+
+	cur := pid
+	for i := 0; i < 64; i++ {
+		ppid := readPPID(cur)
+		if ppid <= 1 || own[ppid] {
+			return 0, false
+		}
+		if session > 0 && readSession(ppid) != session {
+			return 0, false
+		}
+		if !shells[getProcessNameFromPID(ppid)] {
+			return ppid, true
+		}
+		cur = ppid
+	}
+	return 0, false
+}
+
+func newProcNode(pid int) *ProcNode {
+	return &ProcNode{
+		PID:       pid,
+		PPID:      readPPID(pid),
+		Name:      getProcessNameFromPID(pid),
+		StartTime: readStartTime(pid),
+	}
+}
+
+// buildChildrenMap scans /proc once and maps each PPid to its children.
+func buildChildrenMap() map[int][]int {
+	result := make(map[int][]int)
+
+	procEntries, err := os.ReadDir("/proc")
+	if err != nil {
+		return result
+	}
+	for _, e := range procEntries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil || pid <= 0 {
+			continue
+		}
+		if ppid := readPPID(pid); ppid > 0 {
+			result[ppid] = append(result[ppid], pid)
+		}
+	}
+	for _, c := range result {
+		sort.Ints(c)
+	}
+	return result
+}
+
+// ownAncestors returns porty itself and every process above it.
+func ownAncestors() map[int]bool {
+	out := make(map[int]bool)
+	for pid := os.Getpid(); pid > 0 && !out[pid]; pid = readPPID(pid) {
+		out[pid] = true
+	}
+	return out
+}
+
+// IsDescendant reports whether pid is ancestor or runs below it.
+func IsDescendant(pid, ancestor int) bool {
+	seen := make(map[int]bool)
+	for ; pid > 0 && !seen[pid]; pid = readPPID(pid) {
+		if pid == ancestor {
+			return true
+		}
+		seen[pid] = true
+	}
+	return false
+}
+
+// readPPID reads the PPid line of /proc/<pid>/status; 0 when unknown.
+func readPPID(pid int) int {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "status"))
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "PPid:") {
+			v, _ := strconv.Atoi(strings.TrimSpace(line[len("PPid:"):]))
+			return v
+		}
+	}
+	return 0
+}
+
+// readSession returns the session id (field 6) of /proc/<pid>/stat.
+func readSession(pid int) int {
+	fields := readStatFields(pid)
+	if len(fields) < 4 {
+		return 0
+	}
+	v, _ := strconv.Atoi(fields[3])
+	return v
+}
+
+// readStatFields returns the fields of /proc/<pid>/stat that follow the
+// parenthesised comm, which may itself contain spaces. fields[0] is state.
+func readStatFields(pid int) []string {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return nil
+	}
+	s := string(data)
+	i := strings.LastIndexByte(s, ')')
+	if i < 0 {
+		return nil
+	}
+	return strings.Fields(s[i+1:])
+}
@@ -0,0 +1,151 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// PortState is the outcome of waiting for a port to be released.
+type PortState string
+
+const (
+	PortFreed   PortState = "freed"
+	PortHeld    PortState = "held"
+	PortRebound PortState = "rebound"
+)
+
+// PortKey is a port of one protocol: 53/udp and 53/tcp are different ports.
+type PortKey struct {
+	Port  string `json:"port"`
+	Proto string `json:"proto"`
+}
+
+func (k PortKey) String() string {
+	return k.Port + "/" + k.Proto
+}
+
+// KeyOf returns the port and protocol of e.
+func KeyOf(e PortEntry) PortKey {
+	return PortKey{Port: e.LocalPort, Proto: e.Proto}
+}
+
+// PortStatus reports what happened to a port after its owners were signalled.
+// PID is the remaining holder; Parent and Origin are only set for rebound ports.
+type PortStatus struct {
+	PortKey
+	State  PortState `json:"state"`
+	PID    int       `json:"pid,omitempty"`
+	Parent int       `json:"parent,omitempty"`
+	Origin *Origin   `json:"origin,omitempty"`
+}
+
+func (s PortStatus) String() string {
+	switch s.State {
+	case PortFreed:
+		return fmt.Sprintf("port %s freed", s.PortKey)
+	case PortRebound:
+		return fmt.Sprintf("port %s rebound by new PID %d (parent %d)", s.PortKey, s.PID, s.Parent)
+	default:
+		if s.PID == 0 {
+			return fmt.Sprintf("port %s still held (owner unknown)", s.PortKey)
+		}
+		return fmt.Sprintf("port %s still held by PID %d", s.PortKey, s.PID)
+	}
+}
+
+// WaitPortsFreed rescans until every port has no listener, a process that
+// did not hold it before rebinds it, or timeout expires. before is the scan
+// the kill was planned from; its holders count as "still held", anyone else
+// as "rebound".
+func WaitPortsFreed(before []PortEntry, ports []PortKey, timeout, interval time.Duration) []PortStatus {
+	held := holdersByKey(before)
+
+	deadline := time.Now().Add(timeout)
+	result := make(map[PortKey]PortStatus)
+	for {
+		entries, _ := ListPorts()
+		holders := make(map[PortKey][]int)
+		for _, e := range entries {
+			holders[KeyOf(e)] = append(holders[KeyOf(e)], e.PID)
+		}
+
+		pending := 0
+		for _, port := range ports {
+			if st, ok := result[port]; ok && st.State != PortHeld {
+				continue
+			}
+			st := PortStatus{PortKey: port, State: PortFreed}
+			for _, pid := range holders[port] {
+				if held[port][pid] {
+					st = PortStatus{PortKey: port, State: PortHeld, PID: pid}
+					continue
+				}
+				st = reboundStatus(port, pid)
+				break
+			}
+			result[port] = st
+			if st.State == PortHeld {
+				pending++
+			}
+		}
+
+		if pending == 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(interval)
+	}
+
+	out := make([]PortStatus, 0, len(ports))
+	for _, port := range ports {
+		out = append(out, result[port])
+	}
+	return out
+}
+
+// SignalledPorts returns the ports of the entries whose socket or owner
+// results show as signalled, or as already gone. Ports of skipped,
+// protected and failed targets are left out, since they are expected to
+// stay held.
+func SignalledPorts(entries []PortEntry, results []KillResult) []PortKey {
+	pids := make(map[int]bool)
+	inodes := make(map[string]bool)
+	for _, r := range results {
+		if r.Outcome != OutcomeTerminated && r.Outcome != OutcomeNotFound {
+			continue
+		}
+		pids[r.PID] = true
+		if r.Inode != "" {
+			inodes[r.Inode] = true
+		}
+	}
+	seen := make(map[PortKey]bool)
+	var out []PortKey
+	for _, e := range entries {
+		k := KeyOf(e)
+		if (e.PID > 0 && pids[e.PID] || inodes[e.Inode]) && !seen[k] {
+			seen[k] = true
+			out = append(out, k)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Port != out[j].Port {
+			return out[i].Port < out[j].Port
+		}
+		return out[i].Proto < out[j].Proto
+	})
+	return out
+}
+
+// holdersByKey maps each port and protocol to the PIDs holding it.
+func holdersByKey(entries []PortEntry) map[PortKey]map[int]bool {
+	held := make(map[PortKey]map[int]bool)
+	for _, e := range entries {
+		k := KeyOf(e)
+		if held[k] == nil {
+			held[k] = make(map[int]bool)
+		}
+		held[k][e.PID] = true
+	}
+	return held
+}
@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"net"
+	"strconv"
+	"time"
+)
+
+// WaitSpec is what WaitFor waits for: every port listening, or every port
+// free. PID and Owner, when set, limit which holders count: a listener must
+// match them, and a port is free once no matching holder is left.
+type WaitSpec struct {
+	Ports     []int
+	Proto     string
+	Listening bool
+	PID       int
+	// Under, when set, only counts sockets held by that PID or its
+	// descendants, e.g. a server started through npm.
+	Under int
+	// Owner must already be compiled.
+	Owner Selector
+	// Connect also requires a TCP connect to the port on loopback to
+	// succeed before a port counts as listening.
+	Connect bool
+	// Done, when closed, makes WaitFor give up early.
+	Done <-chan struct{}
+}
+
+// WaitResult is the last observed state of one port. Holder is the
+// matching listener, if any.
+type WaitResult struct {
+	Port   int        `json:"port"`
+	Met    bool       `json:"met"`
+	Holder *PortEntry `json:"holder,omitempty"`
+}
+
+// WaitFor rescans every interval until all of spec's ports are in the
+// wanted state or timeout expires; a zero timeout waits forever. It
+// reports whether the wait succeeded, with each port's last state.
+func WaitFor(spec WaitSpec, timeout, interval time.Duration) ([]WaitResult, bool) {
+	wanted := make(map[int]bool, len(spec.Ports))
+	for _, p := range spec.Ports {
+		wanted[p] = true
+	}
+	keep := func(proto string, port int) bool {
+		return wanted[port] && (spec.Proto == "" || spec.Proto == proto)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		entries, _ := ListPortsMatching(keep)
+		results := make([]WaitResult, len(spec.Ports))
+		done := true
+		for i, port := range spec.Ports {
+			r := WaitResult{Port: port}
+			for _, e := range entries {
+				if spec.holds(e, port) {
+					e := e
+					r.Holder = &e
+					break
+				}
+			}
+			if spec.Listening {
+				r.Met = r.Holder != nil && (!spec.Connect || dialLoopback(port, interval))
+			} else {
+				r.Met = r.Holder == nil
+			}
+			results[i] = r
+			done = done && r.Met
+		}
+
+		if done {
+			return results, true
+		}
+		if timeout > 0 && time.Now().After(deadline) {
+			return results, false
+		}
+		select {
+		case <-spec.Done:
+			return results, false
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (spec *WaitSpec) holds(e PortEntry, port int) bool {
+	if e.LocalPort != strconv.Itoa(port) || (spec.Proto != "" && e.Proto != spec.Proto) {
+		return false
+	}
+	if spec.PID != 0 && e.PID != spec.PID {
+		return false
+	}
+	if spec.Under != 0 && !IsDescendant(e.PID, spec.Under) {
+		return false
+	}
+	return spec.Owner.Empty() || spec.Owner.Match(e)
+}
+
+// dialLoopback reports whether a TCP connect to port succeeds on
+// 127.0.0.1 or [::1].
+func dialLoopback(port int, timeout time.Duration) bool {
+	if timeout < 100*time.Millisecond {
+		timeout = 100 * time.Millisecond
+	}
+	for _, host := range []string{"127.0.0.1", "::1"} {
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(port)), timeout)
+		if err == nil {
+			conn.Close()
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/trishan9/porty/internal"
+)
+
+// TableOptions configures WriteTable.
+type TableOptions struct {
+	// Columns to print, by name; nil means DefaultColumns.
+	Columns []string
+	Header  bool
+	Color   bool
+}
+
+// column is one field of the static table.
+type column struct {
+	header string
+	value  func(e internal.PortEntry) string
+	style  func(e internal.PortEntry) lipgloss.Style
+}
+
+// DefaultColumns mirrors the TUI's ports panel.
+var DefaultColumns = []string{"state", "port", "proto", "stack", "addr", "process", "pid", "user", "tag", "label"}
+
+var columns = map[string]column{
+	"state": {header: "STATE", value: func(e internal.PortEntry) string { return e.State },
+		style: func(internal.PortEntry) lipgloss.Style { return lipgloss.NewStyle().Foreground(mutedColor) }},
+	"port": {header: "PORT", value: func(e internal.PortEntry) string { return e.LocalPort },
+		style: func(e internal.PortEntry) lipgloss.Style {
+			if e.Ephemeral {
+				return lipgloss.NewStyle().Foreground(warnColor)
+			}
+			return lipgloss.NewStyle().Foreground(cyanColor)
+		}},
+	"proto": {header: "PROTO", value: func(e internal.PortEntry) string { return e.Proto },
+		style: func(internal.PortEntry) lipgloss.Style { return lipgloss.NewStyle().Foreground(blueColor) }},
+	"stack": {header: "STACK", value: func(e internal.PortEntry) string { return e.Stack },
+		style: func(internal.PortEntry) lipgloss.Style { return lipgloss.NewStyle().Foreground(mutedColor) }},
+	"addr": {header: "ADDR", value: func(e internal.PortEntry) string {
+		if len(e.Addrs) > 0 {
+			return strings.Join(e.Addrs, ",")
+		}
+		return e.LocalAddr
+	}},
+	"process": {header: "PROCESS", value: func(e internal.PortEntry) string { return e.ProcessName }},
+	"pid":     {header: "PID", value: pidString},
+	"user":    {header: "USER", value: func(e internal.PortEntry) string { return e.UserName }},
+	"tag": {header: "TAG", value: func(e internal.PortEntry) string { t, _ := styleTag(e.Tag); return t },
+		style: func(e internal.PortEntry) lipgloss.Style { _, s := styleTag(e.Tag); return s }},
+	"inode": {header: "INODE", value: func(e internal.PortEntry) string { return e.Inode }},
+	"ephemeral": {header: "EPHEMERAL", value: func(e internal.PortEntry) string {
+		if e.Ephemeral {
+			return "yes"
+		}
+		return ""
+	}},
+	"label": {header: "LABEL", value: func(e internal.PortEntry) string { return e.Label },
+		style: func(internal.PortEntry) lipgloss.Style { return lipgloss.NewStyle().Foreground(cyanColor) }},
+}
+
+// ColumnNames lists every column WriteTable knows, defaults first.
+func ColumnNames() []string {
+	return append(append([]string(nil), DefaultColumns...), "inode", "ephemeral")
+}
+
+// ParseColumns splits a comma-separated column list and validates it.
+func ParseColumns(s string) ([]string, error) {
+	var out []string
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("unknown column %q (available: %s)", name, strings.Join(ColumnNames(), ", "))
+		}
+		out = append(out, name)
+	}
+	return out, nil
+}
+
+// WriteTable prints entries as an aligned static table, for pipes and
+// terminals where the interactive UI is not wanted. Column widths are
+// computed from the data.
+func WriteTable(w io.Writer, entries []internal.PortEntry, opts TableOptions) error {
+	names := opts.Columns
+	if len(names) == 0 {
+		names = DefaultColumns
+	}
+	cols := make([]column, 0, len(names))
+	for _, n := range names {
+		c, ok := columns[n]
+		if !ok {
+			return fmt.Errorf("unknown column %q", n)
+		}
+		cols = append(cols, c)
+	}
+
+	widths := make([]int, len(cols))
+	if opts.Header {
+		for i, c := range cols {
+			widths[i] = lipgloss.Width(c.header)
+		}
+	}
+	cells := make([][]string, len(entries))
+	for r, e := range entries {
+		cells[r] = make([]string, len(cols))
+		for i, c := range cols {
+			v := c.value(e)
+			cells[r][i] = v
+			if n := lipgloss.Width(v); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	var b strings.Builder
+	if opts.Header {
+		line := make([]string, len(cols))
+		for i, c := range cols {
+			line[i] = c.header
+		}
+		writeRow(&b, line, widths, func(i int, s string) string {
+			if opts.Color {
+				return lipgloss.NewStyle().Bold(true).Render(s)
+			}
+			return s
+		})
+	}
+	for r, e := range entries {
+		writeRow(&b, cells[r], widths, func(i int, s string) string {
+			if opts.Color && cols[i].style != nil {
+				return cols[i].style(e).Render(s)
+			}
+			return s
+		})
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeRow writes one table line. Cells after the last non-empty one are
+// dropped and that one is not padded, so lines never end in spaces even
+// once style wraps each cell in escape codes.
+func writeRow(b *strings.Builder, cells []string, widths []int, style func(i int, s string) string) {
+	last := len(cells) - 1
+	for last >= 0 && cells[last] == "" {
+		last--
+	}
+	for i := 0; i <= last; i++ {
+		if i > 0 {
+			b.WriteString("  ")
+		}
+		cell := cells[i]
+		if i < last {
+			cell = pad(cell, widths[i])
+		}
+		b.WriteString(style(i, cell))
+	}
+	b.WriteString("\n")
+}
+
+func pad(s string, width int) string {
+	if n := lipgloss.Width(s); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
+
+func pidString(e internal.PortEntry) string {
+	if e.PID > 0 {
+		return strconv.Itoa(e.PID)
+	}
+	return "-"
+}
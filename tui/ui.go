@@ -1,6 +1,7 @@
 package tui
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"strconv"
@@ -15,25 +16,21 @@ import (
 
 // ---------- Tokyo Night palette ----------
 
+// Colors default to internal.DefaultConfig's theme; SetTheme replaces them.
 var (
-    // No backgrounds. Terminal decides the background.
-    textColor    = lipgloss.Color("#c0caf5")
-    mutedColor   = lipgloss.Color("#6b7089")
-
-    successColor = lipgloss.Color("#9ece6a")
-    warnColor    = lipgloss.Color("#e0af68")
-    errorColor   = lipgloss.Color("#f7768e")
-
-    blueColor    = lipgloss.Color("#7aa2f7")
-    cyanColor    = lipgloss.Color("#7dcfff")
-    purpleColor  = lipgloss.Color("#bb9af7")
-
-    // gradients still fine
-    gradientColors = []lipgloss.Color{
-        cyanColor,
-        blueColor,
-        purpleColor,
-    }
+	// No backgrounds. Terminal decides the background.
+	textColor  lipgloss.Color
+	mutedColor lipgloss.Color
+
+	successColor lipgloss.Color
+	warnColor    lipgloss.Color
+	errorColor   lipgloss.Color
+
+	blueColor   lipgloss.Color
+	cyanColor   lipgloss.Color
+	purpleColor lipgloss.Color
+
+	gradientColors []lipgloss.Color
 )
 
 // Cursor highlight background (auto adjusts to terminal theme)
@@ -49,6 +46,36 @@ var cursorFg = lipgloss.AdaptiveColor{
 
 
 var (
+	baseStyle     lipgloss.Style
+	panelStyle    lipgloss.Style
+	helpStyle     lipgloss.Style
+	titleStyle    lipgloss.Style
+	statusSuccess lipgloss.Style
+	statusError   lipgloss.Style
+	statusNeutral lipgloss.Style
+)
+
+func init() {
+	SetTheme(internal.DefaultConfig().Theme)
+}
+
+// SetTheme sets the palette of the TUI and the plain table. Empty colors
+// keep their current value.
+func SetTheme(t internal.Theme) {
+	for _, c := range []struct {
+		dst *lipgloss.Color
+		v   string
+	}{
+		{&textColor, t.Text}, {&mutedColor, t.Muted},
+		{&successColor, t.Success}, {&warnColor, t.Warn}, {&errorColor, t.Error},
+		{&blueColor, t.Blue}, {&cyanColor, t.Cyan}, {&purpleColor, t.Purple},
+	} {
+		if c.v != "" {
+			*c.dst = lipgloss.Color(c.v)
+		}
+	}
+	gradientColors = []lipgloss.Color{cyanColor, blueColor, purpleColor}
+
 	baseStyle = lipgloss.NewStyle().
 		Foreground(textColor)
 
@@ -70,12 +97,65 @@ var (
 	statusSuccess = lipgloss.NewStyle().Foreground(successColor).Margin(0, 2)
 	statusError   = lipgloss.NewStyle().Foreground(errorColor).Margin(0, 2)
 	statusNeutral = lipgloss.NewStyle().Foreground(mutedColor).Margin(0, 2)
-)
+}
 
-const tickInterval = 2 * time.Second
+// actions are the bindable TUI actions in help-line order, with labels.
+var actions = []struct{ name, label string }{
+	{"up", "up"}, {"down", "down"}, {"select", "select"}, {"kill", "kill"},
+	{"tree", "kill tree"}, {"parent", "kill parent"}, {"restart", "restart"},
+	{"elevate", "sudo"}, {"filter", "filter"}, {"label", "label"}, {"reload", "reload"},
+	{"quit", "quit"},
+}
+
+// keyMap resolves keys to actions. Actions k leaves unbound keep their
+// default keys.
+func keyMap(k internal.Keys) (map[string]string, string) {
+	bindings := k.Bindings()
+	defaults := internal.DefaultConfig().Keys.Bindings()
+	byKey := make(map[string]string)
+	var help []string
+	for _, a := range actions {
+		keys := bindings[a.name]
+		if len(keys) == 0 {
+			keys = defaults[a.name]
+		}
+		shown := make([]string, len(keys))
+		for i, key := range keys {
+			byKey[key] = a.name
+			shown[i] = keyLabel(key)
+		}
+		help = append(help, strings.Join(shown, "/")+" "+a.label)
+	}
+	return byKey, strings.Join(help, "  ")
+}
+
+func keyLabel(key string) string {
+	switch key {
+	case "up":
+		return "↑"
+	case "down":
+		return "↓"
+	case " ":
+		return "space"
+	}
+	return key
+}
 
 type tickMsg struct{}
 
+// elevatedMsg carries the reply of an elevated porty once the TUI resumes.
+type elevatedMsg struct {
+	op  string // "kill" or "scan"
+	out *bytes.Buffer
+	err error
+}
+
+// restartMsg carries the outcome of a restart run off the UI goroutine.
+type restartMsg struct {
+	res internal.RestartResult
+	err error
+}
+
 type cpuSample struct {
 	idle  uint64
 	total uint64
@@ -84,25 +164,82 @@ type cpuSample struct {
 // ---------- model ----------
 
 type model struct {
+	opts     Options
 	entries  []internal.PortEntry
 	cursor   int
 	selected map[int]bool
 	status   string
 	statusOK bool
 
+	// results of the last kill, shown per target under the status line.
+	results []internal.KillResult
+
+	// known is the last scan that attributed sockets porty cannot see
+	// unprivileged; refreshes borrow owners from it by inode.
+	known []internal.PortEntry
+
+	// pending holds kill plans awaiting y/n confirmation.
+	pending []internal.KillPlan
+
+	// elevating holds the targets of a retry through the privilege helper
+	// awaiting y/n confirmation.
+	elevating []internal.KillTarget
+
+	// keys maps a key to its action; helpText lists the bindings.
+	keys     map[string]string
+	helpText string
+
+	// filter narrows the rows. prompt is "filter" while / edits it as
+	// input, or "label" while L edits the label of the labeling row.
+	filter   internal.Selector
+	prompt   string
+	input    string
+	labeling internal.PortEntry
+
 	cpuPercent  int
 	memUsedMiB  int
 	memTotalMiB int
 	lastCPU     cpuSample
 }
 
+// Options configures the TUI.
+type Options struct {
+	// Helper re-runs porty with privileges, e.g. "sudo" or "doas".
+	Helper string
+	// Filter is the initial row filter; it must already be compiled.
+	Filter internal.Selector
+	// Sort and Reverse order the rows, as for internal.SortEntries.
+	Sort    string
+	Reverse bool
+	// Refresh is the rescan interval; zero means every 2 seconds.
+	Refresh time.Duration
+	// Keys rebinds actions; unset actions keep their default keys.
+	Keys internal.Keys
+	// Protected processes are skipped instead of killed.
+	Protected internal.Protector
+	// Tags retag every scan, before Filter applies.
+	Tags []internal.TagRule
+	// NoMerge keeps IPv4 and IPv6 sockets on separate rows; see
+	// internal.MergeStacks.
+	NoMerge bool
+}
+
 // NewModel creates the initial TUI model.
-func NewModel(entries []internal.PortEntry) model {
+func NewModel(entries []internal.PortEntry, opts Options) model {
+	if opts.Refresh <= 0 {
+		opts.Refresh = internal.DefaultConfig().RefreshInterval
+	}
+	keys, help := keyMap(opts.Keys)
 	m := model{
+		keys:     keys,
+		helpText: help,
+		opts:     opts,
 		entries:  entries,
+		known:    entries,
 		cursor:   0,
 		selected: make(map[int]bool),
-		status:   "↑/↓/j/k move  space select  enter/x kill  r reload  q quit",
+		filter:   opts.Filter,
+		status:   help,
 		statusOK: true,
 	}
 	m = refreshModel(m) // initial stats/ports snapshot
@@ -110,16 +247,16 @@ func NewModel(entries []internal.PortEntry) model {
 }
 
 // Run launches the Bubble Tea program.
-func Run(entries []internal.PortEntry) error {
-	p := tea.NewProgram(NewModel(entries))
+func Run(entries []internal.PortEntry, opts Options) error {
+	p := tea.NewProgram(NewModel(entries, opts))
 	_, err := p.Run()
 	return err
 }
 
-func (m model) Init() tea.Cmd { return tickCmd() }
+func (m model) Init() tea.Cmd { return tickCmd(m.opts.Refresh) }
 
-func tickCmd() tea.Cmd {
-	return tea.Tick(tickInterval, func(time.Time) tea.Msg {
+func tickCmd(interval time.Duration) tea.Cmd {
+	return tea.Tick(interval, func(time.Time) tea.Msg {
 		return tickMsg{}
 	})
 }
@@ -129,77 +266,358 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case tickMsg:
 		m = refreshModel(m)
-		return m, tickCmd()
+		return m, tickCmd(m.opts.Refresh)
+
+	case elevatedMsg:
+		m.applyElevated(msg)
+		m = refreshModel(m)
+		return m, nil
+
+	case restartMsg:
+		m.setKillResults(audit(msg.res.Kills))
+		if msg.err != nil {
+			m.status = "restart failed: " + msg.err.Error()
+			m.statusOK = false
+		} else {
+			m.status = fmt.Sprintf("port %s restarted as PID %d", msg.res.Context.Port, msg.res.NewPID)
+			m.statusOK = true
+		}
+		m = refreshModel(m)
+		return m, nil
 
 	case tea.KeyMsg:
-		switch msg.String() {
+		if m.pending != nil {
+			return m.updateConfirm(msg)
+		}
+		if m.elevating != nil {
+			return m.updateElevateConfirm(msg)
+		}
+		switch m.prompt {
+		case "filter":
+			return m.updatePrompt(msg)
+		case "label":
+			return m.updateLabelPrompt(msg)
+		}
+
+		action := m.keys[msg.String()]
+		if msg.Type == tea.KeyCtrlC {
+			action = "quit"
+		}
 
-		case "q", "esc", "ctrl+c":
+		switch action {
+
+		case "quit":
 			return m, tea.Quit
 
-		case "up", "k":
+		case "up":
 			if m.cursor > 0 {
 				m.cursor--
 			}
 
-		case "down", "j":
+		case "down":
 			if m.cursor < len(m.entries)-1 {
 				m.cursor++
 			}
 
-		case " ":
+		case "select":
 			if len(m.entries) == 0 {
 				return m, nil
 			}
 			m.selected[m.cursor] = !m.selected[m.cursor]
 
-		case "r":
+		case "reload":
 			m = refreshModel(m)
+			m.results = nil
 			m.status = "reloaded"
 			m.statusOK = true
 
-		case "enter", "x":
+		case "kill":
+			targets := m.killTargets()
+			if len(targets) == 0 {
+				m.status = "no valid PIDs to kill"
+				m.statusOK = false
+				return m, nil
+			}
+			m.setKillResults(m.kill(targets))
+
+		case "elevate":
+			if m.elevating = internal.PermissionDenied(m.results); m.elevating == nil {
+				return m, m.runElevated("scan", nil)
+			}
+			m.status = "y confirm  n cancel"
+			m.statusOK = true
+
+		case "filter":
+			m.prompt = "filter"
+			m.input = m.filter.String()
+
+		case "label":
 			if len(m.entries) == 0 {
 				return m, nil
 			}
-			pids := m.collectSelectedPIDs()
-			if len(pids) == 0 {
-				if pid := m.entries[m.cursor].PID; pid > 0 {
-					pids = []int{pid}
+			m.prompt = "label"
+			m.labeling = m.entries[m.cursor]
+			m.input = m.labeling.Label
+
+		case "restart":
+			if len(m.entries) == 0 {
+				return m, nil
+			}
+			// Restart kills every holder of the port, including those the
+			// filter hides, so guard against a fresh unfiltered scan.
+			key := internal.KeyOf(m.entries[m.cursor])
+			entries, _ := internal.ListPorts()
+			entries = internal.Attribute(entries, m.known)
+			for _, e := range entries {
+				if internal.KeyOf(e) == key && m.opts.Protected.Protects(e.PID, e.ProcessName) {
+					m.status, m.statusOK = fmt.Sprintf("port %s is held by %s, which is protected; not restarting it", key, e.ProcessName), false
+					return m, nil
 				}
 			}
-			if len(pids) == 0 {
+			m.results = nil
+			m.status = "restarting port " + key.String() + "…"
+			m.statusOK = true
+			return m, func() tea.Msg {
+				res, err := internal.Restart(entries, key, internal.RestartOptions{
+					FreeTimeout:   3 * time.Second,
+					ListenTimeout: 30 * time.Second,
+				})
+				return restartMsg{res: res, err: err}
+			}
+
+		case "tree", "parent":
+			targets := m.killTargets()
+			if len(targets) == 0 {
 				m.status = "no valid PIDs to kill"
 				m.statusOK = false
 				return m, nil
 			}
+			tree, parent := action == "tree", action == "parent"
+			for _, t := range targets {
+				m.pending = append(m.pending, internal.PlanKill(t.PID, tree, parent))
+			}
+			m.status = "y confirm  n cancel"
+			m.statusOK = true
+		}
+	}
+	return m, nil
+}
+
+// updateConfirm handles keys while a kill plan preview is shown.
+func (m model) updateConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "y", "enter":
+		results := m.kill(internal.PlanTargets(m.pending))
+		m.pending = nil
+		m.setKillResults(results)
+		m = refreshModel(m)
+	case "n", "esc", "q":
+		m.pending = nil
+		m.status = "kill cancelled"
+		m.statusOK = true
+	case "ctrl+c":
+		return m, tea.Quit
+	}
+	return m, nil
+}
 
-			msgs := internal.KillPIDs(pids)
-			m.status = strings.Join(msgs, " | ")
+// updateElevateConfirm handles keys while the targets of a retry through
+// the privilege helper are shown.
+func (m model) updateElevateConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "y", "enter":
+		req := internal.ElevatedKillRequest{Targets: m.elevating, Protected: m.opts.Protected.Patterns()}
+		m.elevating = nil
+		return m, m.runElevated("kill", req)
+	case "n", "esc", "q":
+		m.elevating = nil
+		m.status = "kill cancelled"
+		m.statusOK = true
+	case "ctrl+c":
+		return m, tea.Quit
+	}
+	return m, nil
+}
 
-			m.statusOK = true
-			for _, s := range msgs {
-				ls := strings.ToLower(s)
-				if strings.Contains(ls, "fail") || strings.Contains(ls, "error") {
-					m.statusOK = false
-					break
+// updatePrompt edits the filter expression typed after /. Enter applies it,
+// or keeps the prompt open and reports the column of a parse error; an
+// empty filter shows every row again, and esc leaves the current one.
+func (m model) updatePrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		f, err := internal.ParseFilter(m.input)
+		if err != nil {
+			m.status, m.statusOK = "filter: "+err.Error(), false
+			return m, nil
+		}
+		m.prompt = ""
+		m.filter = f
+		m.selected = make(map[int]bool)
+		m.cursor = 0
+		m = refreshModel(m)
+		if f.Empty() {
+			m.status, m.statusOK = "filter cleared", true
+		} else {
+			m.status, m.statusOK = fmt.Sprintf("%d ports match %s", len(m.entries), f), true
+		}
+	case tea.KeyEsc:
+		m.prompt = ""
+	default:
+		return m.editInput(msg)
+	}
+	return m, nil
+}
+
+// updateLabelPrompt edits the label of the row L was pressed on. Enter
+// saves it, an empty label removes it, and esc leaves it unchanged. The
+// label being edited is the one porty shows for the row, so a label tied
+// to a process keeps its process and note.
+func (m model) updateLabelPrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		e := m.labeling
+		l := internal.Label{Port: e.LocalPort, Name: strings.TrimSpace(m.input)}
+		if labels, err := internal.LoadLabels(); err == nil {
+			for _, old := range labels {
+				if old.Port == e.LocalPort && old.Name == e.Label && (old.Process == "" || old.Process == e.ProcessName) {
+					l.Process, l.Note = old.Process, old.Note
 				}
 			}
 		}
+		if l.Name == "" {
+			if _, err := internal.RemoveLabel(l.Port, l.Process); err != nil {
+				m.status, m.statusOK = err.Error(), false
+				return m, nil
+			}
+			m.status, m.statusOK = "label of port "+l.Port+" removed", true
+		} else {
+			if err := internal.SetLabel(l); err != nil {
+				m.status, m.statusOK = "label: "+err.Error(), false
+				return m, nil
+			}
+			m.status, m.statusOK = fmt.Sprintf("port %s labelled %q", l.Port, l.Name), true
+		}
+		m.prompt = ""
+		m = refreshModel(m)
+	case tea.KeyEsc:
+		m.prompt = ""
+	default:
+		return m.editInput(msg)
+	}
+	return m, nil
+}
+
+// editInput applies a key typed at a prompt to its input.
+func (m model) editInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	case tea.KeyBackspace:
+		if r := []rune(m.input); len(r) > 0 {
+			m.input = string(r[:len(r)-1])
+		}
+	case tea.KeyCtrlU:
+		m.input = ""
+	case tea.KeySpace:
+		m.input += " "
+	case tea.KeyRunes:
+		m.input += string(msg.Runes)
 	}
 	return m, nil
 }
 
-func (m model) collectSelectedPIDs() []int {
-	var pids []int
+// runElevated suspends the TUI and runs op through the privilege helper:
+// "kill" retries the confirmed targets that failed with EPERM, and "scan"
+// attributes every socket, so rows owned by other users get their PIDs
+// and can be killed (and, failing that, retried) like any other.
+func (m model) runElevated(op string, payload any) tea.Cmd {
+	cmd, out, err := internal.ElevatedCommand(m.opts.Helper, op, payload)
+	if err != nil {
+		return func() tea.Msg { return elevatedMsg{op: op, err: err} }
+	}
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return elevatedMsg{op: op, out: out, err: err}
+	})
+}
+
+func (m *model) applyElevated(msg elevatedMsg) {
+	if msg.op == "scan" {
+		var entries []internal.PortEntry
+		if err := internal.DecodeElevated(msg.out, msg.err, &entries); err != nil {
+			m.status, m.statusOK = err.Error(), false
+			return
+		}
+		m.known = entries
+		m.status, m.statusOK = "sockets attributed with elevated privileges", true
+		return
+	}
+
+	var retried []internal.KillResult
+	if err := internal.DecodeElevated(msg.out, msg.err, &retried); err != nil {
+		m.status, m.statusOK = err.Error(), false
+		return
+	}
+	m.setKillResults(internal.MergeResults(m.results, audit(retried)))
+}
+
+// kill signals targets, skipping protected processes, and audits the
+// outcome.
+func (m model) kill(targets []internal.KillTarget) []internal.KillResult {
+	allowed, skipped := m.opts.Protected.Guard(targets)
+	return audit(append(skipped, internal.KillTargets(allowed)...))
+}
+
+// audit records kills made from the TUI. The status line already reports
+// the outcome, so a log failure must not turn a kill into an error.
+func audit(results []internal.KillResult) []internal.KillResult {
+	_ = internal.AppendAudit(results, "tui")
+	return results
+}
+
+// killTargets returns the selected rows, or the row under the cursor, as
+// targets bound to the socket they were displayed with. m.entries can be a
+// tick old, so the kill re-validates each one before signalling.
+func (m model) killTargets() []internal.KillTarget {
+	if len(m.entries) == 0 {
+		return nil
+	}
+	targets := m.collectSelectedTargets()
+	if len(targets) == 0 {
+		if e := m.entries[m.cursor]; e.PID > 0 {
+			targets = []internal.KillTarget{internal.TargetForEntry(e)}
+		}
+	}
+	return targets
+}
+
+// setKillResults records the outcome of a kill for per-target display.
+func (m *model) setKillResults(results []internal.KillResult) {
+	m.results = results
+
+	ok := 0
+	for _, r := range results {
+		if r.OK() {
+			ok++
+		}
+	}
+	if len(results) == 0 {
+		m.status = "no valid PIDs to kill"
+	} else {
+		m.status = fmt.Sprintf("signalled %d of %d", ok, len(results))
+	}
+	m.statusOK = len(results) > 0 && ok == len(results)
+}
+
+func (m model) collectSelectedTargets() []internal.KillTarget {
+	var targets []internal.KillTarget
 	for idx, sel := range m.selected {
 		if sel && idx >= 0 && idx < len(m.entries) {
-			if pid := m.entries[idx].PID; pid > 0 {
-				pids = append(pids, pid)
+			if e := m.entries[idx]; e.PID > 0 {
+				targets = append(targets, internal.TargetForEntry(e))
 			}
 		}
 	}
-	return pids
+	return targets
 }
 
 // ---------- refresh ----------
@@ -207,8 +625,22 @@ func (m model) collectSelectedPIDs() []int {
 func refreshModel(m model) model {
 	// refresh ports
 	if entries, err := internal.ListPorts(); err == nil {
+		entries = internal.Attribute(entries, m.known)
+		labels, _ := internal.LoadLabels()
+		internal.ApplyLabels(entries, labels)
+		internal.ApplyTags(entries, m.opts.Tags)
+		if !m.filter.Empty() {
+			entries = m.filter.Select(entries)
+		}
+		if !m.opts.NoMerge {
+			entries = internal.MergeStacks(entries)
+		}
+		_ = internal.SortEntries(entries, m.opts.Sort, m.opts.Reverse)
 		m.entries = entries
 	}
+	if m.cursor >= len(m.entries) {
+		m.cursor = max(len(m.entries)-1, 0)
+	}
 
 	// refresh memory
 	used, total := readMem()
@@ -306,6 +738,12 @@ func (m model) View() string {
 
 	// vertical layout works nicely on most widths; lipgloss handles wrapping
 	main := lipgloss.JoinVertical(lipgloss.Left, portsPanel)
+	if m.pending != nil {
+		main = lipgloss.JoinVertical(lipgloss.Left, portsPanel, m.renderPlanPanel())
+	}
+	if m.elevating != nil {
+		main = lipgloss.JoinVertical(lipgloss.Left, portsPanel, m.renderElevatePanel())
+	}
 
 	var statusLine string
 	if m.status == "" {
@@ -316,15 +754,33 @@ func (m model) View() string {
 		statusLine = statusError.Render(m.status)
 	}
 
-	help := helpStyle.Render("↑/↓/j/k move  space select  enter/x kill  r reload  q quit")
+	for _, r := range m.results {
+		statusLine += "\n" + renderResult(r)
+	}
+
+	help := helpStyle.Render(m.helpText)
+	switch m.prompt {
+	case "filter":
+		help = helpStyle.Render("/ " + m.input + "█   enter apply  esc cancel  (port:3000-3999 user:me, or port >= 3000 && !(process ~ \"docker\"))")
+	case "label":
+		help = helpStyle.Render("label :" + m.labeling.LocalPort + " " + m.input + "█   enter save  esc cancel  (empty removes it)")
+	}
+
+	title := "PORTY – Listening Ports"
+	if !m.filter.Empty() {
+		title += "  [" + m.filter.String() + "]"
+	}
 
 	return baseStyle.Render(
-		titleStyle.Render("PORTY – Listening Ports") + "\n\n" +
+		titleStyle.Render(title) + "\n\n" +
 			main + "\n" + help + "\n" + statusLine + "\n",
 	)
 }
 
 func (m model) renderPortsPanel() string {
+	if len(m.entries) == 0 && !m.filter.Empty() {
+		return panelStyle.Render("No ports match the filter.")
+	}
 	if len(m.entries) == 0 {
 		return panelStyle.Render("No listening ports detected.")
 	}
@@ -335,12 +791,14 @@ func (m model) renderPortsPanel() string {
 	b.WriteString(header + "\n\n")
 
 	// table header
-	headerLine := fmt.Sprintf("  %-3s %-2s %-7s %-6s %-6s %-22s %-8s %-12s %-8s",
-		"#", " ", "STATE", "PORT", "PROTO", "PROCESS", "PID", "USER", "TAG")
+	headerLine := fmt.Sprintf("  %-3s %-2s %-7s %-6s %-6s %-5s %-18s %-22s %-8s %-12s %-8s %-16s",
+		"#", " ", "STATE", "PORT", "PROTO", "STACK", "ADDR", "PROCESS", "PID", "USER", "TAG", "LABEL")
 	b.WriteString(headerLine + "\n")
 	b.WriteString(strings.Repeat("─", len(headerLine)) + "\n")
 
+	ephemeral := false
 	for i, e := range m.entries {
+		ephemeral = ephemeral || e.Ephemeral
 		cursor := " "
 		if i == m.cursor {
 			cursor = "▸"
@@ -353,8 +811,17 @@ func (m model) renderPortsPanel() string {
 		idxStr := fmt.Sprintf("%2d", i+1)
 
 		portStr := gradientText(fmt.Sprintf("%-6s", e.LocalPort), gradientColors)
+		if e.Ephemeral {
+			portStr = lipgloss.NewStyle().Foreground(warnColor).Render(fmt.Sprintf("%-6s", e.LocalPort+"*"))
+		}
 		protoStr := lipgloss.NewStyle().Foreground(blueColor).Render(e.Proto)
+		stackStr := lipgloss.NewStyle().Foreground(mutedColor).Render(fmt.Sprintf("%-5s", e.Stack))
 		stateStr := lipgloss.NewStyle().Foreground(mutedColor).Render(e.State)
+		addrs := e.LocalAddr
+		if len(e.Addrs) > 0 {
+			addrs = strings.Join(e.Addrs, ",")
+		}
+		addrStr := lipgloss.NewStyle().Foreground(mutedColor).Render(fmt.Sprintf("%-18s", truncate(addrs, 18)))
 
 		proc := truncate(e.ProcessName, 22)
 		user := truncate(e.UserName, 12)
@@ -367,8 +834,10 @@ func (m model) renderPortsPanel() string {
 			pidStr = fmt.Sprintf("%d", e.PID)
 		}
 
-		row := fmt.Sprintf("  %-3s %s %-7s %s %-6s %-22s %-8s %-12s %-8s",
-			idxStr, check, stateStr, portStr, protoStr, proc, pidStr, user, tagRendered)
+		label := lipgloss.NewStyle().Foreground(cyanColor).Render(truncate(e.Label, 16))
+
+		row := fmt.Sprintf("  %-3s %s %-7s %s %-6s %s %s %-22s %-8s %-12s %-8s %-16s",
+			idxStr, check, stateStr, portStr, protoStr, stackStr, addrStr, proc, pidStr, user, tagRendered, label)
 
 		if i == m.cursor {
 			row = lipgloss.NewStyle().
@@ -382,7 +851,58 @@ func (m model) renderPortsPanel() string {
 
 		b.WriteString(row + "\n")
 	}
+	if ephemeral {
+		b.WriteString("\n" + lipgloss.NewStyle().Foreground(warnColor).Render("* inside the ephemeral port range: outgoing connections can take it (porty ephemeral)") + "\n")
+	}
+
+	return panelStyle.Render(b.String())
+}
+
+// renderResult draws one kill target's outcome, colored by how it went.
+func renderResult(r internal.KillResult) string {
+	port := ""
+	if r.Port != "" {
+		port = " :" + r.Port
+	}
+	line := fmt.Sprintf("PID %d %s%s %s", r.PID, r.Process, port, r.Outcome)
+	if r.Err != nil {
+		line += ": " + r.Err.Error()
+	}
+	switch r.Outcome {
+	case internal.OutcomeTerminated:
+		return statusSuccess.Render("✓ " + line)
+	case internal.OutcomeSkipped, internal.OutcomeNotFound:
+		return lipgloss.NewStyle().Foreground(warnColor).Margin(0, 2).Render("○ " + line)
+	default:
+		return statusError.Render("✗ " + line)
+	}
+}
+
+func (m model) renderPlanPanel() string {
+	var b strings.Builder
+	b.WriteString(gradientText(" ABOUT TO SIGNAL ", gradientColors) + "\n\n")
+	for _, p := range m.pending {
+		b.WriteString(p.Render() + "\n")
+	}
+	b.WriteString("\n" + lipgloss.NewStyle().Foreground(warnColor).Render("y confirm  n cancel"))
+	return panelStyle.Render(b.String())
+}
 
+func (m model) renderElevatePanel() string {
+	helper := m.opts.Helper
+	if helper == "" {
+		helper = internal.DefaultHelper
+	}
+	var b strings.Builder
+	b.WriteString(gradientText(" ABOUT TO SIGNAL THROUGH "+strings.ToUpper(helper)+" ", gradientColors) + "\n\n")
+	for _, t := range m.elevating {
+		line := fmt.Sprintf("%d %s", t.PID, t.Process)
+		if t.Port != "" {
+			line += " :" + t.Port
+		}
+		b.WriteString(line + "\n")
+	}
+	b.WriteString("\n" + lipgloss.NewStyle().Foreground(warnColor).Render("y confirm  n cancel"))
 	return panelStyle.Render(b.String())
 }
 
@@ -412,8 +932,11 @@ func styleTag(tag string) (string, lipgloss.Style) {
         return "SELF", lipgloss.NewStyle().Foreground(cyanColor)
     case "KERNEL":
         return "KERNEL", lipgloss.NewStyle().Foreground(purpleColor).Bold(true)
-    default:
+    case "", "UNKNOWN":
         return "UNKNOWN", lipgloss.NewStyle().Foreground(errorColor)
+    default:
+        // custom tags from the config file
+        return tag, lipgloss.NewStyle().Foreground(blueColor)
     }
 }
 